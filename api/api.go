@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// The OpenAPI description of the JSON API that gator serve provides
+//
+//go:embed openapi.yaml
+var Spec []byte
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"html"
+	"strings"
+)
+
+// Atom feeds have <feed><entry> where RSS has <rss><channel><item>. They're converted to an
+// RSSFeed as they're parsed, so the aggregator only deals with one shape.
+type atomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      atomText      `xml:"title"`
+	Links      []atomLink    `xml:"link"`
+	Summary    atomText      `xml:"summary"`
+	Content    atomText      `xml:"content"`
+	Published  string        `xml:"published"`
+	Updated    string        `xml:"updated"`
+	Authors    []RSSAuthor   `xml:"author"`
+	Categories []RSSCategory `xml:"category"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// Atom text is plain text, escaped html or inline xhtml depending on its type attribute
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// Returns the text as html, which is what RSS descriptions hold
+func (t atomText) HTML() string {
+	switch t.Type {
+	case "html":
+		return t.Text
+	case "xhtml":
+		return t.Inner
+	default:
+		return html.EscapeString(t.Text)
+	}
+}
+
+// Returns the text as plain text, for titles
+func (t atomText) Plain() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// The page an entry or feed links to, the alternate link if there's more than one
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	if len(links) > 0 {
+		return strings.TrimSpace(links[0].Href)
+	}
+	return ""
+}
+
+func (f atomFeed) toRSS() RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = f.Title
+	feed.Channel.Link = alternateLink(f.Links)
+	feed.Channel.Description = f.Subtitle
+
+	for _, entry := range f.Entries {
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       entry.Title.Plain(),
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary.HTML(),
+			PubDate:     strings.TrimSpace(published),
+			Content:     entry.Content.HTML(),
+			Authors:     entry.Authors,
+			Categories:  entry.Categories,
+		})
+	}
+	return feed
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// The migration whose indexes db bench measures the queries with and without
+const benchIndexMigration = "014_indexes"
+
+// Rows are seeded this many at a time, so a big seed doesn't sit in one huge transaction
+const benchBatchSize = 5000
+
+type benchQuery struct {
+	name string
+	run  func(ctx context.Context, store database.Store) error
+}
+
+// Seeds a scratch database with synthetic feeds and posts, then times browse and the
+// aggregator's queries with and without the indexes, to show what they're worth at scale
+func handlerBench(args []string) error {
+	fs := flag.NewFlagSet("db bench", flag.ExitOnError)
+	posts := fs.Int("posts", 200000, "number of posts to seed")
+	feeds := fs.Int("feeds", 200, "number of feeds to spread the posts over")
+	runs := fs.Int("runs", 5, "times to run each query, the median is reported")
+	dbURL := fs.String("db", "", "an empty database to use instead of a temporary SQLite file")
+	args = parseFlags(fs, args)
+
+	if len(args) != 0 || *posts < 1 || *feeds < 4 || *runs < 1 {
+		return errors.New(dbUsage)
+	}
+
+	dsn := *dbURL
+	if dsn == "" {
+		dir, err := os.MkdirTemp("", "gator-bench-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		dsn = "sqlite://" + filepath.Join(dir, "bench.db")
+	}
+
+	db, err := database.Connect(dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = database.Migrate(db)
+	if err != nil {
+		return err
+	}
+	store := database.NewStore(db)
+	ctx := context.Background()
+
+	users, err := store.GetUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return fmt.Errorf("db bench seeds its own data and needs an empty database, %s has users in it", dsn)
+	}
+	// Whatever happens, don't leave the synthetic data behind in a database we were given
+	if *dbURL != "" {
+		defer store.ResetUsers(ctx)
+	}
+
+	fmt.Printf("Seeding %d posts across %d feeds...\n", *posts, *feeds)
+	start := time.Now()
+	user, err := seedBench(ctx, store, *posts, *feeds)
+	if err != nil {
+		return fmt.Errorf("error seeding benchmark data: %w", err)
+	}
+	fmt.Printf("Seeded in %s\n\n", time.Since(start).Round(time.Millisecond))
+
+	queries, err := benchQueries(ctx, store, user)
+	if err != nil {
+		return err
+	}
+
+	var without, with []time.Duration
+	err = database.WithoutMigration(db, benchIndexMigration, func() error {
+		var err error
+		without, err = timeQueries(ctx, store, queries, *runs)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	with, err = timeQueries(ctx, store, queries, *runs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("  %-36s  %16s  %16s\n", "query", "without indexes", "with indexes")
+	for i, q := range queries {
+		fmt.Printf("  %-36s  %16s  %16s\n", q.name, without[i].Round(time.Microsecond), with[i].Round(time.Microsecond))
+	}
+	return nil
+}
+
+// Adds a user who follows a quarter of the feeds, with half of those in a folder, and spreads
+// the posts evenly over every feed with publish dates from the last two years
+func seedBench(ctx context.Context, store database.Store, posts, feeds int) (database.User, error) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	now := time.Now()
+
+	var user database.User
+	var feedIDs []uuid.UUID
+	err := store.InTx(ctx, func(tx database.Store) error {
+		var err error
+		user, err = tx.CreateUser(ctx, database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Name:      "bench",
+		})
+		if err != nil {
+			return err
+		}
+
+		folder, err := tx.CreateFolder(ctx, database.CreateFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    user.ID,
+			Name:      "bench",
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range feeds {
+			feed, err := tx.CreateFeed(ctx, database.CreateFeedParams{
+				ID:            uuid.New(),
+				CreatedAt:     now,
+				UpdatedAt:     now,
+				Name:          fmt.Sprintf("feed-%d", i),
+				Url:           fmt.Sprintf("https://bench.invalid/%d/feed.xml", i),
+				UserID:        user.ID,
+				LastFetchedAt: now.Add(-time.Duration(rng.IntN(24*60)) * time.Minute),
+			})
+			if err != nil {
+				return err
+			}
+			feedIDs = append(feedIDs, feed.ID)
+
+			if i%4 != 0 {
+				continue
+			}
+			_, err = tx.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if i%8 == 0 {
+				_, err = tx.SetFollowFolder(ctx, database.SetFollowFolderParams{
+					UserID:    user.ID,
+					FeedID:    feed.ID,
+					FolderID:  uuid.NullUUID{UUID: folder.ID, Valid: true},
+					UpdatedAt: now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return user, err
+	}
+
+	for seeded := 0; seeded < posts; seeded += benchBatchSize {
+		err = store.InTx(ctx, func(tx database.Store) error {
+			for i := seeded; i < min(seeded+benchBatchSize, posts); i++ {
+				published := now.Add(-time.Duration(rng.Int64N(int64(2 * 365 * 24 * time.Hour))))
+				post, err := tx.CreatePost(ctx, database.CreatePostParams{
+					ID:          uuid.New(),
+					CreatedAt:   now,
+					UpdatedAt:   now,
+					Title:       fmt.Sprintf("Post %d", i),
+					Url:         fmt.Sprintf("https://bench.invalid/posts/%d", i),
+					Description: "A synthetic post for db bench.",
+					PublishedAt: published.UTC().Format(time.RFC3339),
+					FeedID:      feedIDs[i%len(feedIDs)],
+				})
+				if err != nil {
+					return err
+				}
+				err = tx.CreatePostAuthor(ctx, database.CreatePostAuthorParams{
+					PostID: post.ID,
+					Name:   fmt.Sprintf("Author %d", i%50),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return user, err
+		}
+	}
+	return user, nil
+}
+
+// The queries behind browse, open and agg, as they're run against a big database
+func benchQueries(ctx context.Context, store database.Store, user database.User) ([]benchQuery, error) {
+	browse := func(arg database.GetPostsForUserParams) func(ctx context.Context, store database.Store) error {
+		arg.UserID = user.ID
+		return func(ctx context.Context, store database.Store) error {
+			_, err := store.GetPostsForUser(ctx, arg)
+			return err
+		}
+	}
+
+	// A cursor deep into the user's posts, as if they'd paged through 100 pages of 10
+	deep, err := store.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: user.ID, MaxPosts: 1000})
+	if err != nil {
+		return nil, err
+	}
+	if len(deep) == 0 {
+		return nil, errors.New("the seeded user has no posts to browse")
+	}
+	cursor := deep[len(deep)-1]
+
+	return []benchQuery{
+		{"browse, every post (before LIMIT)", browse(database.GetPostsForUserParams{MaxPosts: math.MaxInt32})},
+		{"browse 10", browse(database.GetPostsForUserParams{MaxPosts: 10})},
+		{"browse 10 --before (page 100)", browse(database.GetPostsForUserParams{
+			MaxPosts:          10,
+			BeforePublishedAt: sql.NullString{String: cursor.PublishedAt, Valid: true},
+			BeforeID:          uuid.NullUUID{UUID: cursor.ID, Valid: true},
+		})},
+		{"browse 10 --folder", browse(database.GetPostsForUserParams{MaxPosts: 10, Folder: "bench"})},
+		{"open 500", browse(database.GetPostsForUserParams{MaxPosts: 500})},
+		{"next feed to fetch", func(ctx context.Context, store database.Store) error {
+			_, err := store.GetNextFeedToFetch(ctx)
+			return err
+		}},
+	}, nil
+}
+
+// Runs each query the given number of times and returns the median time of each
+func timeQueries(ctx context.Context, store database.Store, queries []benchQuery, runs int) ([]time.Duration, error) {
+	var medians []time.Duration
+	for _, q := range queries {
+		times := make([]time.Duration, runs)
+		for i := range times {
+			start := time.Now()
+			err := q.run(ctx, store)
+			if err != nil {
+				return nil, fmt.Errorf("error running %s: %w", q.name, err)
+			}
+			times[i] = time.Since(start)
+		}
+		slices.Sort(times)
+		medians = append(medians, times[runs/2])
+	}
+	return medians, nil
+}
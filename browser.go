@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Opens link with $BROWSER, falling back to the platform's default opener. Links come from
+// feeds, so only absolute http and https urls are passed on, never files, other schemes or
+// anything a browser could take for a command line flag.
+func openInBrowser(link string) error {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("won't open %q, only http and https links can be opened", link)
+	}
+	target := u.String()
+
+	var cmd *exec.Cmd
+
+	// $BROWSER can hold a colon separated list of commands, use the first one
+	if fields := strings.Fields(strings.Split(os.Getenv("BROWSER"), ":")[0]); len(fields) > 0 {
+		cmd = exec.Command(fields[0], append(fields[1:], target)...)
+	} else {
+		switch runtime.GOOS {
+		case "linux", "freebsd", "openbsd", "netbsd":
+			cmd = exec.Command("xdg-open", target)
+		case "darwin":
+			cmd = exec.Command("open", target)
+		case "windows":
+			cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+		default:
+			return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+		}
+	}
+
+	// Don't tie the browser's lifetime or output to ours
+	cmd.Stdin = nil
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+
+	go cmd.Wait()
+	return nil
+}
+
+// Asks the terminal to put text on the system clipboard with an OSC 52 escape sequence
+func copyToClipboard(w io.Writer, text string) {
+	fmt.Fprintf(w, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+}
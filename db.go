@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/archive"
+	"github.com/Daxin319/Gator/internal/database"
+)
+
+const dbUsage = `usage:
+  db bootstrap [--yes]                  start a local PostgreSQL server if needed and create gator's database
+  db migrate up                         apply any migrations the database doesn't have yet
+  db migrate down                       roll back the most recent migration
+  db migrate status                     list migrations and whether they have been applied
+  db backup <file>                      save everything to an archive, gzipped if the name ends in .gz
+  db restore <file>                     load an archive into an empty database
+  db restore --replace [--yes] <file>   delete everything in the database first and load the archive instead
+  db prune [--dry-run]                  remove posts older than the retention settings allow, agg does this hourly
+  db bench [--posts N] [--feeds N] [--runs N] [--db url]
+                                        time browse and agg's queries against synthetic data, with and without indexes`
+
+func handlerDB(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		return errors.New(dbUsage)
+	}
+
+	args := cmd.arguments[1:]
+
+	// Benchmarks bring their own scratch database rather than using the configured one
+	if cmd.arguments[0] == "bench" {
+		return handlerBench(args)
+	}
+
+	switch cmd.arguments[0] {
+	case "bootstrap":
+		fs := flag.NewFlagSet("db bootstrap", flag.ExitOnError)
+		yes := fs.Bool("yes", false, "don't ask before each step")
+		parseFlags(fs, args)
+
+		ask := func(question string) bool { return confirm(s, question) }
+		if *yes {
+			ask = func(string) bool { return true }
+		}
+
+		err := database.Bootstrap(s.config.DatabaseURL(), ask)
+		if errors.Is(err, database.ErrAborted) {
+			return errors.New("Aborted, nothing else was changed.")
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(s.out, "GatorDB is ready!")
+
+	case "migrate":
+		if len(args) != 1 {
+			return errors.New(dbUsage)
+		}
+		return handlerMigrate(s, args[0])
+
+	case "backup":
+		if len(args) != 1 {
+			return errors.New(dbUsage)
+		}
+		stats, err := archive.WriteFile(context.Background(), s.db, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "Saved %s to %s\n", stats, args[0])
+
+	case "restore":
+		fs := flag.NewFlagSet("db restore", flag.ExitOnError)
+		replace := fs.Bool("replace", false, "delete everything in the database first")
+		yes := fs.Bool("yes", false, "don't ask for confirmation")
+		args = parseFlags(fs, args)
+
+		if len(args) != 1 {
+			return errors.New(dbUsage)
+		}
+		return handlerRestore(s, args[0], *replace, *yes)
+
+	case "prune":
+		fs := flag.NewFlagSet("db prune", flag.ExitOnError)
+		dryRun := fs.Bool("dry-run", false, "only show what would be pruned")
+		args = parseFlags(fs, args)
+
+		if len(args) != 0 {
+			return errors.New(dbUsage)
+		}
+		return handlerPrune(s, *dryRun)
+
+	default:
+		return errors.New(dbUsage)
+	}
+
+	return nil
+}
+
+func handlerMigrate(s *state, direction string) error {
+	switch direction {
+	case "up":
+		err := database.Migrate(s.sqlDB)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(s.out, "Database schema is up to date.")
+
+	case "down":
+		migration, err := database.MigrateDown(s.sqlDB)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "Rolled back %s\n", migration.Name)
+		fmt.Fprintln(s.out, "Any other gator command will apply it again, install the older gator before using it.")
+
+	case "status":
+		status, err := database.GetMigrationStatus(s.sqlDB)
+		if err != nil {
+			return err
+		}
+		for _, migration := range status {
+			if migration.Applied {
+				fmt.Fprintf(s.out, "  applied  %s  %s\n", migration.AppliedAt.Format(time.DateTime), migration.Name)
+			} else {
+				fmt.Fprintf(s.out, "  pending  %-19s  %s\n", "", migration.Name)
+			}
+		}
+
+	default:
+		return errors.New(dbUsage)
+	}
+
+	return nil
+}
+
+func handlerRestore(s *state, path string, replace, yes bool) error {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return fmt.Errorf("error getting users from database: %w", err)
+	}
+
+	if len(users) > 0 {
+		if !replace {
+			return fmt.Errorf("the database already has data in it, use --replace to delete it all and restore %s instead", path)
+		}
+
+		user, err := s.db.GetUser(context.Background(), s.config.CurrentUserName)
+		if err != nil || !user.IsAdmin {
+			return fmt.Errorf("only admins can replace the database")
+		}
+		if !yes && !confirm(s, fmt.Sprintf("Delete everything in the database and restore %s instead?", path)) {
+			return errors.New("Aborted, nothing was changed.")
+		}
+
+		snapshot, err := snapshotPath("restore")
+		if err != nil {
+			return err
+		}
+		stats, err := archive.WriteFile(context.Background(), s.db, snapshot)
+		if err != nil {
+			return fmt.Errorf("error saving snapshot, nothing was changed: %w", err)
+		}
+		fmt.Fprintf(s.out, "Saved a snapshot of %s to %s\n", stats, snapshot)
+	}
+
+	// Either the whole archive goes in, or the database stays as it was
+	var stats archive.Stats
+	err = s.inTx(func(s *state) error {
+		err := s.db.ResetUsers(context.Background())
+		if err != nil {
+			return fmt.Errorf("error clearing database: %w", err)
+		}
+		stats, err = archive.ReadFile(context.Background(), s.db, path)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Restored %s from %s\n", stats, path)
+	return nil
+}
+
+// Returns the subcommand of a db command, db commands manage the connection and schema themselves
+func dbSubcommand(cmd command) string {
+	if cmd.name != "db" || len(cmd.arguments) == 0 {
+		return ""
+	}
+	return cmd.arguments[0]
+}
+
+// Asks a yes or no question on the terminal, anything but yes counts as no
+func confirm(s *state, question string) bool {
+	fmt.Fprintf(s.out, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Fprintln(s.out)
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const feedUsage = `usage:
+  feed edit <feed> [--name name] [--url url]   change a feed's name or url
+  feed delete <feed>                           delete a feed along with its posts and follows
+  feed merge <old> <new>                       move followers and posts from one feed to another, then delete the old one
+  feed fulltext <feed> on|off                  download each new article from a feed that only has teasers
+  feed retention <feed> [--days N] [--posts N] [--default]
+                                               show or change how long a feed's posts are kept`
+
+func handlerFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return errors.New(feedUsage)
+	}
+
+	args := cmd.arguments[1:]
+
+	switch cmd.arguments[0] {
+	case "edit":
+		fs := flag.NewFlagSet("feed edit", flag.ExitOnError)
+		name := fs.String("name", "", "new name for the feed")
+		url := fs.String("url", "", "new url for the feed")
+		args = parseFlags(fs, args)
+
+		if len(args) != 1 {
+			return fmt.Errorf("expecting 1 argument (feed id, name or url)")
+		}
+		if *name == "" && *url == "" {
+			return fmt.Errorf("nothing to change, use --name and/or --url")
+		}
+
+		feed, err := getManagedFeed(s, user, args[0])
+		if err != nil {
+			return err
+		}
+
+		params := database.UpdateFeedParams{
+			ID:        feed.ID,
+			Name:      feed.Name,
+			Url:       feed.Url,
+			UpdatedAt: time.Now(),
+		}
+		if *name != "" {
+			params.Name = *name
+		}
+		newURL := feed.Url
+		if *url != "" {
+			newURL = *url
+		}
+		err = validateFeed(params.Name, newURL)
+		if err != nil {
+			return err
+		}
+
+		var updated database.Feed
+		err = s.inTx(func(s *state) error {
+			if *url != "" && *url != feed.Url {
+				err := setFeedURL(s, feed.ID, feed.Url, *url)
+				if err != nil {
+					return err
+				}
+				params.Url = *url
+
+				// A new url is a fresh start for a feed that had gone away
+				if !feed.Active {
+					err = s.db.SetFeedActive(context.Background(), database.SetFeedActiveParams{
+						ID:        feed.ID,
+						Active:    true,
+						UpdatedAt: time.Now(),
+					})
+					if err != nil {
+						return fmt.Errorf("error updating feed: %w", err)
+					}
+				}
+			}
+
+			updated, err = s.db.UpdateFeed(context.Background(), params)
+			if err != nil {
+				return fmt.Errorf("error updating feed: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "Updated %s  %s  %s\n", shortID(updated.ID), updated.Name, updated.Url)
+
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("expecting 1 argument (feed id, name or url)")
+		}
+
+		feed, err := getManagedFeed(s, user, args[0])
+		if err != nil {
+			return err
+		}
+
+		// Follows, posts and their read states all go with the feed through the foreign keys
+		err = s.db.DeleteFeed(context.Background(), feed.ID)
+		if err != nil {
+			return fmt.Errorf("error deleting feed: %w", err)
+		}
+		fmt.Fprintf(s.out, "Deleted %s\n", feed.Name)
+
+	case "retention":
+		return handlerFeedRetention(s, args, user)
+
+	case "fulltext":
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			return fmt.Errorf("expecting 2 arguments (feed id, name or url, on|off)")
+		}
+
+		feed, err := getManagedFeed(s, user, args[0])
+		if err != nil {
+			return err
+		}
+
+		err = s.db.SetFeedFullText(context.Background(), database.SetFeedFullTextParams{
+			Url:           feed.Url,
+			FetchFullText: args[1] == "on",
+			UpdatedAt:     time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("error updating feed: %w", err)
+		}
+		fmt.Fprintf(s.out, "Full text extraction for %s is now %s\n", feed.Name, args[1])
+
+	case "merge":
+		if len(args) != 2 {
+			return fmt.Errorf("expecting 2 arguments (old feed, new feed)")
+		}
+
+		oldFeed, err := getManagedFeed(s, user, args[0])
+		if err != nil {
+			return err
+		}
+		newFeed, err := lookupFeed(s, args[1])
+		if err != nil {
+			return err
+		}
+		if oldFeed.ID == newFeed.ID {
+			return fmt.Errorf("can't merge a feed into itself")
+		}
+
+		// Either all of the old feed ends up in the new one, or nothing changes
+		var moved, posts, archived int64
+		err = s.inTx(func(s *state) error {
+			var err error
+
+			// Users already following the new feed keep that follow, their old one goes with the old feed
+			moved, err = s.db.MoveFeedFollows(context.Background(), database.MoveFeedFollowsParams{
+				NewFeedID: newFeed.ID,
+				UpdatedAt: time.Now(),
+				OldFeedID: oldFeed.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("error moving feed_follow records: %w", err)
+			}
+
+			// Post urls are unique across feeds so the posts can move as they are, keeping read and starred states
+			posts, err = s.db.MoveFeedPosts(context.Background(), database.MoveFeedPostsParams{
+				NewFeedID: newFeed.ID,
+				UpdatedAt: time.Now(),
+				OldFeedID: oldFeed.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("error moving posts: %w", err)
+			}
+
+			// Archived and pruned posts would otherwise go with the old feed
+			archived, err = s.db.MoveArchivedPosts(context.Background(), database.MoveArchivedPostsParams{
+				NewFeedID: newFeed.ID,
+				UpdatedAt: time.Now(),
+				OldFeedID: oldFeed.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("error moving archived posts: %w", err)
+			}
+			err = s.db.MovePrunedPosts(context.Background(), database.MovePrunedPostsParams{
+				NewFeedID: newFeed.ID,
+				OldFeedID: oldFeed.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("error moving pruned posts: %w", err)
+			}
+
+			// Keep the old feed's urls pointing somewhere so lookups and imports by them still work
+			err = s.db.MoveFeedURLHistory(context.Background(), database.MoveFeedURLHistoryParams{
+				NewFeedID: newFeed.ID,
+				OldFeedID: oldFeed.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("error moving feed url history: %w", err)
+			}
+
+			err = s.db.DeleteFeed(context.Background(), oldFeed.ID)
+			if err != nil {
+				return fmt.Errorf("error deleting feed: %w", err)
+			}
+
+			err = s.db.AddFeedURLHistory(context.Background(), database.AddFeedURLHistoryParams{
+				Url:     oldFeed.Url,
+				FeedID:  newFeed.ID,
+				MovedAt: time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("error saving feed url history: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(s.out, "Merged %s into %s, moved %d followers, %d posts and %d archived posts\n", oldFeed.Name, newFeed.Name, moved, posts, archived)
+
+	default:
+		return errors.New(feedUsage)
+	}
+
+	return nil
+}
+
+// Points a feed at a new url and remembers the old one, so lookups and imports by the old url still find it
+func setFeedURL(s *state, feedID uuid.UUID, oldURL, newURL string) error {
+	// Two feeds can't share a url, point the user at merge instead of failing on the constraint
+	existing, err := s.db.URLLookup(context.Background(), newURL)
+	if err == nil && existing.ID != feedID {
+		return fmt.Errorf("%s already belongs to %s, use: gator feed merge %s %s", newURL, existing.Name, shortID(feedID), shortID(existing.ID))
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error looking up feed url: %w", err)
+	}
+
+	err = s.db.AddFeedURLHistory(context.Background(), database.AddFeedURLHistoryParams{
+		Url:     oldURL,
+		FeedID:  feedID,
+		MovedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving feed url history: %w", err)
+	}
+
+	// Moving back to an old url takes it out of the history
+	err = s.db.DeleteFeedURLHistory(context.Background(), newURL)
+	if err != nil {
+		return fmt.Errorf("error updating feed url history: %w", err)
+	}
+
+	err = s.db.SetFeedURL(context.Background(), database.SetFeedURLParams{
+		ID:        feedID,
+		Url:       newURL,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating feed url: %w", err)
+	}
+	return nil
+}
+
+// Finds a feed and makes sure the user is allowed to change it
+func getManagedFeed(s *state, user database.User, ref string) (database.Feed, error) {
+	feed, err := lookupFeed(s, ref)
+	if err != nil {
+		return feed, err
+	}
+	if !canManageFeed(user, feed) {
+		return feed, fmt.Errorf("only the user who added %s or an admin can change it", feed.Name)
+	}
+	return feed, nil
+}
+
+// Feeds are shared, so only the user who added one or an admin may change it
+func canManageFeed(user database.User, feed database.Feed) bool {
+	return user.IsAdmin || feed.UserID == user.ID
+}
+
+const adminUsage = `usage:
+  admin grant <user>    make a user an admin
+  admin revoke <user>   take admin rights away from a user`
+
+func handlerAdmin(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) != 2 {
+		return errors.New(adminUsage)
+	}
+	if !user.IsAdmin {
+		return fmt.Errorf("only admins can change who is an admin")
+	}
+
+	var isAdmin bool
+	switch strings.ToLower(cmd.arguments[0]) {
+	case "grant":
+		isAdmin = true
+	case "revoke":
+		isAdmin = false
+		if cmd.arguments[1] == user.Name {
+			return fmt.Errorf("you can't revoke your own admin rights")
+		}
+	default:
+		return errors.New(adminUsage)
+	}
+
+	updated, err := s.db.SetUserAdmin(context.Background(), database.SetUserAdminParams{
+		Name:      cmd.arguments[1],
+		IsAdmin:   isAdmin,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating user: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("no user named %s", cmd.arguments[1])
+	}
+
+	if isAdmin {
+		fmt.Fprintf(s.out, "%s is now an admin\n", cmd.arguments[1])
+	} else {
+		fmt.Fprintf(s.out, "%s is no longer an admin\n", cmd.arguments[1])
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const folderUsage = `usage:
+  folder create <folder>        create a folder
+  folder add <feed> <folder>    move a followed feed into a folder
+  folder rm <folder> [feed]     take a feed out of a folder, or delete the folder
+  folder ls                     list your folders`
+
+func handlerFolder(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return errors.New(folderUsage)
+	}
+
+	args := cmd.arguments[1:]
+
+	switch cmd.arguments[0] {
+	case "create":
+		if len(args) != 1 {
+			return fmt.Errorf("expecting 1 argument (folder name)")
+		}
+		if _, err := getFolder(s, user, args[0]); err == nil {
+			return fmt.Errorf("folder %s already exists", args[0])
+		}
+		folder, err := createFolder(s, user, args[0])
+		if err != nil {
+			return fmt.Errorf("error creating folder: %w", err)
+		}
+		fmt.Fprintf(s.out, "Created folder %s\n", folder.Name)
+
+	case "add":
+		if len(args) != 2 {
+			return fmt.Errorf("expecting 2 arguments (feed id, name or url, folder name)")
+		}
+		feed, err := lookupFeed(s, args[0])
+		if err != nil {
+			return err
+		}
+		folder, err := getFolder(s, user, args[1])
+		if err != nil {
+			return err
+		}
+		err = setFollowFolder(s, user, feed, uuid.NullUUID{UUID: folder.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "Moved %s to %s\n", feed.Name, folder.Name)
+
+	case "rm":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("expecting 1 or 2 arguments (folder name, optional feed id, name or url)")
+		}
+		folder, err := getFolder(s, user, args[0])
+		if err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			feed, err := lookupFeed(s, args[1])
+			if err != nil {
+				return err
+			}
+			err = setFollowFolder(s, user, feed, uuid.NullUUID{})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(s.out, "Removed %s from %s\n", feed.Name, folder.Name)
+			return nil
+		}
+		// Follows in the folder are kept, the foreign key moves them back to unfiled
+		err = s.db.DeleteFolder(context.Background(), folder.ID)
+		if err != nil {
+			return fmt.Errorf("error deleting folder: %w", err)
+		}
+		fmt.Fprintf(s.out, "Deleted folder %s\n", folder.Name)
+
+	case "ls":
+		folders, err := s.db.GetFoldersForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("error getting folders: %w", err)
+		}
+		for _, folder := range folders {
+			fmt.Fprintf(s.out, "* %s\n", folder.Name)
+		}
+
+	default:
+		return errors.New(folderUsage)
+	}
+
+	return nil
+}
+
+// Finds one of the user's folders by name, ignoring case
+func getFolder(s *state, user database.User, name string) (database.Folder, error) {
+	folder, err := s.db.GetFolderByName(context.Background(), database.GetFolderByNameParams{
+		UserID: user.ID,
+		Lower:  name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return folder, fmt.Errorf("no folder named %s, create it with: gator folder create %s", name, name)
+	}
+	return folder, err
+}
+
+func createFolder(s *state, user database.User, name string) (database.Folder, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return database.Folder{}, fmt.Errorf("folder name can't be empty")
+	}
+
+	return s.db.CreateFolder(context.Background(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      name,
+	})
+}
+
+func setFollowFolder(s *state, user database.User, feed database.Feed, folderID uuid.NullUUID) error {
+	updated, err := s.db.SetFollowFolder(context.Background(), database.SetFollowFolderParams{
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		FolderID:  folderID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating feed_follow record: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s isn't following %s", user.Name, feed.Name)
+	}
+	return nil
+}
@@ -0,0 +1,726 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Archives are JSON Lines: a header, then one line per row. Rows are written with their own
+// column names rather than anything tied to a database, so an archive taken from one backend
+// can be loaded into another. Version 2 added the retention settings and the pruned and
+// archived posts, version 3 the API tokens. Older archives still load as they are.
+const (
+	Format  = "gator-archive"
+	Version = 3
+)
+
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// A single row, Table says which of the row types Row holds
+type Line struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	IsAdmin   bool      `json:"is_admin"`
+}
+
+type Feed struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Name           string     `json:"name"`
+	Url            string     `json:"url"`
+	UserID         uuid.UUID  `json:"user_id"`
+	LastFetchedAt  time.Time  `json:"last_fetched_at"`
+	FetchFullText  bool       `json:"fetch_full_text"`
+	Active         bool       `json:"active"`
+	DeactivatedAt  *time.Time `json:"deactivated_at,omitempty"`
+	RetentionDays  *int32     `json:"retention_days,omitempty"`
+	RetentionPosts *int32     `json:"retention_posts,omitempty"`
+}
+
+type Folder struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+}
+
+type FeedFollow struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uuid.UUID  `json:"user_id"`
+	FeedID     uuid.UUID  `json:"feed_id"`
+	FolderID   *uuid.UUID `json:"folder_id,omitempty"`
+	Title      string     `json:"title,omitempty"`
+	NotifiedAt time.Time  `json:"notified_at"`
+}
+
+type FeedURLHistory struct {
+	Url     string    `json:"url"`
+	FeedID  uuid.UUID `json:"feed_id"`
+	MovedAt time.Time `json:"moved_at"`
+}
+
+type Post struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	PublishedAt string    `json:"published_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	Content     string    `json:"content,omitempty"`
+}
+
+type PostName struct {
+	PostID uuid.UUID `json:"post_id"`
+	Name   string    `json:"name"`
+}
+
+type PostState struct {
+	UserID    uuid.UUID `json:"user_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	Read      bool      `json:"read"`
+	Starred   bool      `json:"starred"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ArchivedPost struct {
+	Post
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+type PrunedPost struct {
+	Url      string    `json:"url"`
+	FeedID   uuid.UUID `json:"feed_id"`
+	PrunedAt time.Time `json:"pruned_at"`
+}
+
+// Only the token's hash is kept, like in the database, so an archive can't be used to log in
+type APIToken struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	TokenHash string    `json:"token_hash"`
+}
+
+// Table names in the order rows are written, which is also an order they can be loaded back in
+// without breaking a foreign key
+const (
+	TableUsers          = "users"
+	TableFeeds          = "feeds"
+	TableFolders        = "folders"
+	TableFeedFollows    = "feed_follows"
+	TableFeedURLHistory = "feed_url_history"
+	TablePosts          = "posts"
+	TablePostAuthors    = "post_authors"
+	TablePostCategories = "post_categories"
+	TablePostStates     = "post_states"
+	TableArchivedPosts  = "archived_posts"
+	TablePrunedPosts    = "pruned_posts"
+	TableAPITokens      = "api_tokens"
+)
+
+// Counts of the rows written or read, by table
+type Stats map[string]int
+
+func (s Stats) String() string {
+	var parts []string
+	for _, table := range []string{TableUsers, TableFeeds, TableFolders, TableFeedFollows, TableFeedURLHistory, TablePosts, TablePostAuthors, TablePostCategories, TablePostStates, TableArchivedPosts, TablePrunedPosts, TableAPITokens} {
+		if s[table] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", s[table], strings.ReplaceAll(table, "_", " ")))
+		}
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullInt32(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+func nullUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+type writer struct {
+	enc   *json.Encoder
+	stats Stats
+}
+
+func (w *writer) row(table string, row any) error {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("error encoding %s row: %w", table, err)
+	}
+	err = w.enc.Encode(Line{Table: table, Row: data})
+	if err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	w.stats[table]++
+	return nil
+}
+
+// Writes everything in store to w as an archive, reading it all in one transaction
+func Write(ctx context.Context, store database.Store, w io.Writer) (Stats, error) {
+	var stats Stats
+	err := store.InTx(ctx, func(tx database.Store) error {
+		var err error
+		stats, err = writeRows(ctx, tx, w)
+		return err
+	})
+	return stats, err
+}
+
+func writeRows(ctx context.Context, store database.Store, w io.Writer) (Stats, error) {
+	out := &writer{enc: json.NewEncoder(w), stats: Stats{}}
+	err := out.enc.Encode(Header{Format: Format, Version: Version, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, fmt.Errorf("error writing archive: %w", err)
+	}
+
+	users, err := store.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading users: %w", err)
+	}
+	for _, u := range users {
+		err = out.row(TableUsers, User{
+			ID:        u.ID,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+			Name:      u.Name,
+			IsAdmin:   u.IsAdmin,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	feeds, err := store.ListFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading feeds: %w", err)
+	}
+	for _, f := range feeds {
+		err = out.row(TableFeeds, Feed{
+			ID:             f.ID,
+			CreatedAt:      f.CreatedAt,
+			UpdatedAt:      f.UpdatedAt,
+			Name:           f.Name,
+			Url:            f.Url,
+			UserID:         f.UserID,
+			LastFetchedAt:  f.LastFetchedAt,
+			FetchFullText:  f.FetchFullText,
+			Active:         f.Active,
+			DeactivatedAt:  nullTime(f.DeactivatedAt),
+			RetentionDays:  nullInt32(f.RetentionDays),
+			RetentionPosts: nullInt32(f.RetentionPosts),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	folders, err := store.ListFolders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading folders: %w", err)
+	}
+	for _, f := range folders {
+		err = out.row(TableFolders, Folder{
+			ID:        f.ID,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+			UserID:    f.UserID,
+			Name:      f.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	follows, err := store.ListFeedFollows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading feed follows: %w", err)
+	}
+	for _, f := range follows {
+		err = out.row(TableFeedFollows, FeedFollow{
+			ID:         f.ID,
+			CreatedAt:  f.CreatedAt,
+			UpdatedAt:  f.UpdatedAt,
+			UserID:     f.UserID,
+			FeedID:     f.FeedID,
+			FolderID:   nullUUID(f.FolderID),
+			Title:      f.Title,
+			NotifiedAt: f.NotifiedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	history, err := store.ListFeedURLHistory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading feed url history: %w", err)
+	}
+	for _, h := range history {
+		err = out.row(TableFeedURLHistory, FeedURLHistory{
+			Url:     h.Url,
+			FeedID:  h.FeedID,
+			MovedAt: h.MovedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	posts, err := store.ListPosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading posts: %w", err)
+	}
+	for _, p := range posts {
+		err = out.row(TablePosts, Post{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Content:     p.Content,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	authors, err := store.ListPostAuthors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading post authors: %w", err)
+	}
+	for _, a := range authors {
+		err = out.row(TablePostAuthors, PostName{PostID: a.PostID, Name: a.Name})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	categories, err := store.ListPostCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading post categories: %w", err)
+	}
+	for _, c := range categories {
+		err = out.row(TablePostCategories, PostName{PostID: c.PostID, Name: c.Name})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	states, err := store.ListPostStates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading post states: %w", err)
+	}
+	for _, s := range states {
+		err = out.row(TablePostStates, PostState{
+			UserID:    s.UserID,
+			PostID:    s.PostID,
+			Read:      s.Read,
+			Starred:   s.Starred,
+			UpdatedAt: s.UpdatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	archived, err := store.ListArchivedPosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading archived posts: %w", err)
+	}
+	for _, p := range archived {
+		err = out.row(TableArchivedPosts, ArchivedPost{
+			Post: Post{
+				ID:          p.ID,
+				CreatedAt:   p.CreatedAt,
+				UpdatedAt:   p.UpdatedAt,
+				Title:       p.Title,
+				Url:         p.Url,
+				Description: p.Description,
+				PublishedAt: p.PublishedAt,
+				FeedID:      p.FeedID,
+				Content:     p.Content,
+			},
+			ArchivedAt: p.ArchivedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pruned, err := store.ListPrunedPosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pruned posts: %w", err)
+	}
+	for _, p := range pruned {
+		err = out.row(TablePrunedPosts, PrunedPost{
+			Url:      p.Url,
+			FeedID:   p.FeedID,
+			PrunedAt: p.PrunedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := store.ListAPITokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading api tokens: %w", err)
+	}
+	for _, t := range tokens {
+		err = out.row(TableAPITokens, APIToken{
+			ID:        t.ID,
+			CreatedAt: t.CreatedAt,
+			UserID:    t.UserID,
+			Name:      t.Name,
+			TokenHash: t.TokenHash,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return out.stats, nil
+}
+
+// Writes an archive of store to path, gzipped when the name ends in .gz. The file only
+// appears once the archive is complete.
+func WriteFile(ctx context.Context, store database.Store, path string) (Stats, error) {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	buf := bufio.NewWriter(tmp)
+	var w io.Writer = buf
+	var zw *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		zw = gzip.NewWriter(buf)
+		w = zw
+	}
+
+	stats, err := Write(ctx, store, w)
+	if err != nil {
+		return nil, err
+	}
+	if zw != nil {
+		err = zw.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", path, err)
+		}
+	}
+	err = buf.Flush()
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return stats, nil
+}
+
+func sqlNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func sqlNullInt32(n *int32) sql.NullInt32 {
+	if n == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *n, Valid: true}
+}
+
+func sqlNullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+// Loads an archive, gzipped or not, from r into store. Rows go in as they are read, so the
+// caller should run it as one unit of work and throw everything away if it fails.
+func Read(ctx context.Context, store database.Store, r io.Reader) (Stats, error) {
+	buf := bufio.NewReader(r)
+	var in io.Reader = buf
+	if magic, err := buf.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(buf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+		defer zr.Close()
+		in = zr
+	}
+
+	dec := json.NewDecoder(in)
+
+	var header Header
+	err := dec.Decode(&header)
+	if err != nil || header.Format != Format || header.Version < 1 {
+		return nil, fmt.Errorf("not a gator archive")
+	}
+	if header.Version > Version {
+		return nil, fmt.Errorf("archive version %d is newer than this gator understands (%d), upgrade gator to restore it", header.Version, Version)
+	}
+
+	stats := Stats{}
+	for lineNumber := 2; ; lineNumber++ {
+		var line Line
+		err := dec.Decode(&line)
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive line %d: %w", lineNumber, err)
+		}
+
+		err = restoreRow(ctx, store, line)
+		if err != nil {
+			return nil, fmt.Errorf("error restoring archive line %d: %w", lineNumber, err)
+		}
+		stats[line.Table]++
+	}
+}
+
+func restoreRow(ctx context.Context, store database.Store, line Line) error {
+	switch line.Table {
+	case TableUsers:
+		var u User
+		if err := json.Unmarshal(line.Row, &u); err != nil {
+			return err
+		}
+		_, err := store.CreateUser(ctx, database.CreateUserParams{
+			ID:        u.ID,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+			Name:      u.Name,
+			IsAdmin:   u.IsAdmin,
+		})
+		return err
+
+	case TableFeeds:
+		var f Feed
+		if err := json.Unmarshal(line.Row, &f); err != nil {
+			return err
+		}
+		return store.RestoreFeed(ctx, database.RestoreFeedParams{
+			ID:             f.ID,
+			CreatedAt:      f.CreatedAt,
+			UpdatedAt:      f.UpdatedAt,
+			Name:           f.Name,
+			Url:            f.Url,
+			UserID:         f.UserID,
+			LastFetchedAt:  f.LastFetchedAt,
+			FetchFullText:  f.FetchFullText,
+			Active:         f.Active,
+			DeactivatedAt:  sqlNullTime(f.DeactivatedAt),
+			RetentionDays:  sqlNullInt32(f.RetentionDays),
+			RetentionPosts: sqlNullInt32(f.RetentionPosts),
+		})
+
+	case TableFolders:
+		var f Folder
+		if err := json.Unmarshal(line.Row, &f); err != nil {
+			return err
+		}
+		_, err := store.CreateFolder(ctx, database.CreateFolderParams{
+			ID:        f.ID,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+			UserID:    f.UserID,
+			Name:      f.Name,
+		})
+		return err
+
+	case TableFeedFollows:
+		var f FeedFollow
+		if err := json.Unmarshal(line.Row, &f); err != nil {
+			return err
+		}
+		return store.RestoreFeedFollow(ctx, database.RestoreFeedFollowParams{
+			ID:         f.ID,
+			CreatedAt:  f.CreatedAt,
+			UpdatedAt:  f.UpdatedAt,
+			UserID:     f.UserID,
+			FeedID:     f.FeedID,
+			FolderID:   sqlNullUUID(f.FolderID),
+			Title:      f.Title,
+			NotifiedAt: f.NotifiedAt,
+		})
+
+	case TableFeedURLHistory:
+		var h FeedURLHistory
+		if err := json.Unmarshal(line.Row, &h); err != nil {
+			return err
+		}
+		return store.AddFeedURLHistory(ctx, database.AddFeedURLHistoryParams{
+			Url:     h.Url,
+			FeedID:  h.FeedID,
+			MovedAt: h.MovedAt,
+		})
+
+	case TablePosts:
+		var p Post
+		if err := json.Unmarshal(line.Row, &p); err != nil {
+			return err
+		}
+		_, err := store.CreatePost(ctx, database.CreatePostParams{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Content:     p.Content,
+		})
+		return err
+
+	case TablePostAuthors:
+		var a PostName
+		if err := json.Unmarshal(line.Row, &a); err != nil {
+			return err
+		}
+		return store.CreatePostAuthor(ctx, database.CreatePostAuthorParams{PostID: a.PostID, Name: a.Name})
+
+	case TablePostCategories:
+		var c PostName
+		if err := json.Unmarshal(line.Row, &c); err != nil {
+			return err
+		}
+		return store.CreatePostCategory(ctx, database.CreatePostCategoryParams{PostID: c.PostID, Name: c.Name})
+
+	case TablePostStates:
+		var s PostState
+		if err := json.Unmarshal(line.Row, &s); err != nil {
+			return err
+		}
+		return store.RestorePostState(ctx, database.RestorePostStateParams{
+			UserID:    s.UserID,
+			PostID:    s.PostID,
+			Read:      s.Read,
+			Starred:   s.Starred,
+			UpdatedAt: s.UpdatedAt,
+		})
+
+	case TableArchivedPosts:
+		var p ArchivedPost
+		if err := json.Unmarshal(line.Row, &p); err != nil {
+			return err
+		}
+		return store.RestoreArchivedPost(ctx, database.RestoreArchivedPostParams{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Content:     p.Content,
+			ArchivedAt:  p.ArchivedAt,
+		})
+
+	case TablePrunedPosts:
+		var p PrunedPost
+		if err := json.Unmarshal(line.Row, &p); err != nil {
+			return err
+		}
+		return store.RestorePrunedPost(ctx, database.RestorePrunedPostParams{
+			Url:      p.Url,
+			FeedID:   p.FeedID,
+			PrunedAt: p.PrunedAt,
+		})
+
+	case TableAPITokens:
+		var t APIToken
+		if err := json.Unmarshal(line.Row, &t); err != nil {
+			return err
+		}
+		_, err := store.CreateAPIToken(ctx, database.CreateAPITokenParams{
+			ID:        t.ID,
+			CreatedAt: t.CreatedAt,
+			UserID:    t.UserID,
+			Name:      t.Name,
+			TokenHash: t.TokenHash,
+		})
+		return err
+
+	default:
+		return fmt.Errorf("unknown table %q", line.Table)
+	}
+}
+
+// Loads the archive at path into store, see Read
+func ReadFile(ctx context.Context, store database.Store, path string) (Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(ctx, store, f)
+}
@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/database/dbtest"
+	"github.com/google/uuid"
+)
+
+// A user with a token, a feed they follow with a post they've starred, and a pruned post
+func seed(t *testing.T, s database.Store) {
+	t.Helper()
+	ctx := context.Background()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user := dbtest.CreateUser(t, s, "alice", true)
+	_, err := s.CreateAPIToken(ctx, database.CreateAPITokenParams{ID: uuid.New(), CreatedAt: dbtest.Time, UserID: user.ID, Name: "laptop", TokenHash: "abc123"})
+	must(err)
+	feed := dbtest.CreateFeed(t, s, user, "Blog", "https://example.com/feed")
+	dbtest.Follow(t, s, user, feed)
+	kept := dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Kept", Day: 1, Authors: []string{"Doe, Jane"}})
+	must(s.SetPostStarred(ctx, database.SetPostStarredParams{UserID: user.ID, PostID: kept.ID, Starred: true, UpdatedAt: dbtest.Time}))
+	pruned := dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Pruned", Day: 2, Authors: []string{"Doe, Jane"}})
+	must(s.ArchivePost(ctx, database.ArchivePostParams{ID: pruned.ID, ArchivedAt: dbtest.Time}))
+	must(s.AddPrunedPost(ctx, database.AddPrunedPostParams{ID: pruned.ID, PrunedAt: dbtest.Time}))
+	must(s.DeletePost(ctx, pruned.ID))
+}
+
+// Every row in store, in a form that can be compared across backends
+func dump(t *testing.T, s database.Store) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Write(context.Background(), s, &buf); err != nil {
+		t.Fatal(err)
+	}
+	// Drop the header, it has the time the archive was written
+	_, rows, _ := strings.Cut(buf.String(), "\n")
+	return rows
+}
+
+func TestRoundTrip(t *testing.T) {
+	backends := dbtest.Backends(t)
+	for _, from := range backends {
+		for _, to := range backends {
+			// Opening the one PostgreSQL database again would empty the source
+			if from.Name == "postgres" && to.Name == "postgres" {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s to %s", from.Name, to.Name), func(t *testing.T) {
+				source := from.Open(t)
+				seed(t, source)
+
+				var buf bytes.Buffer
+				written, err := Write(context.Background(), source, &buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if written[TableAPITokens] != 1 {
+					t.Errorf("wrote %v, want the api token", written)
+				}
+
+				target := to.Open(t)
+				read, err := Read(context.Background(), target, &buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if read.String() != written.String() {
+					t.Errorf("read %s, wrote %s", read, written)
+				}
+				if want, got := dump(t, source), dump(t, target); got != want {
+					t.Errorf("restored rows differ\nwant:\n%s\ngot:\n%s", want, got)
+				}
+
+				user, err := target.GetUserByAPIToken(context.Background(), "abc123")
+				if err != nil || user.Name != "alice" {
+					t.Errorf("GetUserByAPIToken after restoring = %+v, %v, want alice", user, err)
+				}
+			})
+		}
+	}
+}
+
+func TestReadChecksTheHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		wantErr string
+	}{
+		{"not json", "hello\n", "not a gator archive"},
+		{"another format", `{"format":"other","version":1}` + "\n", "not a gator archive"},
+		{"newer version", fmt.Sprintf(`{"format":%q,"version":%d}`+"\n", Format, Version+1), "upgrade gator"},
+		{"unknown table", fmt.Sprintf(`{"format":%q,"version":1}`+"\n"+`{"table":"nope","row":{}}`+"\n", Format), `unknown table "nope"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(context.Background(), database.NewMemoryStore(), strings.NewReader(tt.archive))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Read error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
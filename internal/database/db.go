@@ -3,12 +3,17 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type DBTX interface {
@@ -27,106 +32,304 @@ type Queries struct {
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	// Queries on a SQLite transaction still need translating
+	if _, ok := q.db.(sqliteDB); ok {
+		return &Queries{
+			db: sqliteDB{tx},
+		}
+	}
 	return &Queries{
 		db: tx,
 	}
 }
 
-// PostgreSQL connection details
+type txStarter interface {
+	BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+}
 
-const (
-	dbHost = "localhost"
-	dbPort = 5432
-	dbUser = "postgres"
-	dbPass = "postgres"
-	dbName = "gator"
-)
+// Runs fn as a single unit of work. Everything fn does through the Store it's given is
+// committed together if fn returns nil, and rolled back otherwise. Calls made while
+// already inside a transaction join it.
+func (q *Queries) InTx(ctx context.Context, fn func(Store) error) error {
+	db := q.db
+	if s, ok := db.(sqliteDB); ok {
+		db = s.db
+	}
+	starter, ok := db.(txStarter)
+	if !ok {
+		return fn(q)
+	}
 
-// Check if PostgreSQL server is running
-func isPostgresRunning() bool {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPass, dbName)
+	tx, err := starter.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	// Does nothing once the transaction is committed, and undoes it if fn fails or panics
+	defer tx.Rollback()
+
+	err = fn(q.WithTx(tx))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Database every PostgreSQL server has, used to check on and create gator's own database
+const maintenanceDB = "postgres"
+
+var dbnameOption = regexp.MustCompile(`(?:^|\s)dbname\s*=\s*('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Splits a connection string in either url or key=value form into the database it names
+// and a connection string for the same server's maintenance database
+func maintenanceDSN(dsn string) (string, string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid database url: %w", err)
+		}
+		name := strings.TrimPrefix(u.Path, "/")
+		u.Path = "/" + maintenanceDB
+		u.RawPath = ""
+		return u.String(), name, nil
+	}
+
+	name := ""
+	if match := dbnameOption.FindStringSubmatch(dsn); match != nil {
+		name = strings.Trim(match[1], "'")
+	}
+	// lib/pq lets later options override earlier ones
+	return dsn + " dbname=" + maintenanceDB, name, nil
+}
+
+var hostOption = regexp.MustCompile(`(?:^|\s)host\s*=\s*('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Returns the server a connection string points at, without any credentials
+func dsnHost(dsn string) string {
+	host := ""
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			host = u.Host
+		}
+	} else if match := hostOption.FindStringSubmatch(dsn); match != nil {
+		host = strings.Trim(match[1], "'")
+	}
+	if host == "" {
+		return "localhost"
+	}
+	return host
+}
+
+// Only a server on this machine can be started by gator
+func isLocalHost(host string) bool {
+	if strings.HasPrefix(host, "/") {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// Opens the database and makes sure it answers, explaining the likely fix when it doesn't.
+// db_url picks the backend, sqlite: urls open a local file and anything else is PostgreSQL.
+func Connect(dsn string) (*sql.DB, error) {
+	if IsSQLite(dsn) {
+		return openSQLite(dsn)
+	}
 
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		return false
+		return nil, fmt.Errorf("ERROR: Invalid database url: %w", err)
+	}
+
+	err = db.Ping()
+	if err == nil {
+		return db, nil
+	}
+	db.Close()
+
+	host := dsnHost(dsn)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "3D000":
+			return nil, fmt.Errorf("ERROR: %v\nCreate it with: gator db bootstrap", err)
+		case "28000", "28P01":
+			return nil, fmt.Errorf("ERROR: PostgreSQL at %s refused the login: %v\nCheck the user and password in db_url in ~/.gatorconfig.json, or in $GATOR_DB_URL", host, err)
+		}
+		return nil, fmt.Errorf("ERROR: Cannot connect to PostgreSQL at %s: %w", host, err)
+	}
+
+	hint := "Check that the server is up and that db_url in ~/.gatorconfig.json, or $GATOR_DB_URL, points at it"
+	if isLocalHost(host) {
+		hint = "Start PostgreSQL, or have gator start it and create the database with: gator db bootstrap"
+	}
+	return nil, fmt.Errorf("ERROR: Cannot connect to PostgreSQL at %s: %v\n%s", host, err, hint)
+}
+
+// Check if PostgreSQL server is running
+func pingPostgres(adminDSN string) error {
+	db, err := sql.Open("postgres", adminDSN)
+	if err != nil {
+		return err
 	}
 	defer db.Close()
 
 	// Check connection
-	err = db.Ping()
-	return err == nil
+	return db.Ping()
 }
 
-// Try to start PostgreSQL
-func startPostgres() error {
-	var cmd *exec.Cmd
+// The command that starts PostgreSQL as a system service on this OS
+func startCommand() (*exec.Cmd, error) {
 	switch runtime.GOOS {
 	case "linux":
-		cmd = exec.Command("sudo", "systemctl", "start", "postgresql")
+		return exec.Command("sudo", "systemctl", "start", "postgresql"), nil
 	case "darwin":
-		cmd = exec.Command("brew", "services", "start", "postgresql")
+		return exec.Command("brew", "services", "start", "postgresql"), nil
 	case "windows":
-		cmd = exec.Command("net", "start", "postgresql-x64-16")
+		return exec.Command("net", "start", "postgresql-x64-16"), nil
 	default:
-		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+		return nil, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
 	}
+}
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to start PostgreSQL: %s - %s", err, string(output))
+// The command that creates a brand new PostgreSQL data directory
+func initCommand() *exec.Cmd {
+	return exec.Command("sudo", "-u", "postgres", "initdb", "-D", "/var/lib/postgresql/data")
+}
+
+// Shows a command, asks before running it and passes its output through
+func runConfirmed(cmd *exec.Cmd, question string, confirm func(string) bool) error {
+	fmt.Printf("  %s\n", strings.Join(cmd.Args, " "))
+	if !confirm(question) {
+		return ErrAborted
 	}
 
-	return nil
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
 }
 
-// Ensure postgres is running, else start it
-func EnsurePostgresRunning() {
-	if isPostgresRunning() {
-		fmt.Println("PostgreSQL is already running.")
-		return
+// Returned by Bootstrap when the user answers no to one of its questions
+var ErrAborted = errors.New("aborted")
+
+// Gets PostgreSQL running on this machine if it isn't, creates gator's database and applies the migrations.
+// Every step that changes the system is shown first and only run if confirm says yes.
+func Bootstrap(dsn string, confirm func(question string) bool) error {
+	// A SQLite file needs no server, it only has to be created
+	if IsSQLite(dsn) {
+		db, err := openSQLite(dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return Migrate(db)
 	}
 
-	fmt.Println("PostgreSQL is not running. Attempting to start it...")
-	err := startPostgres()
+	adminDSN, name, err := maintenanceDSN(dsn)
 	if err != nil {
-		fmt.Println("ERROR:", err)
-		fmt.Println("Attempting to initialize PostgreSQL...")
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("ERROR: No database name in db_url")
+	}
 
-		// Try initializing PostgreSQL if it's not yet set up
-		err = initializePostgres()
-		if err != nil {
-			fmt.Println("CRITICAL ERROR: Failed to initialize PostgreSQL:", err)
-			os.Exit(1)
-		}
+	host := dsnHost(dsn)
+	fmt.Printf("Checking for PostgreSQL at %s...\n", host)
 
-		// Retry starting PostgreSQL after initialization
-		err = startPostgres()
+	err = pingPostgres(adminDSN)
+	var pqErr *pq.Error
+	switch {
+	case err == nil:
+		fmt.Println("PostgreSQL is already running.")
+	case errors.As(err, &pqErr):
+		// The server answered, so starting it again won't help
+		return fmt.Errorf("ERROR: PostgreSQL is running but refused the connection: %w\nCheck the user and password in db_url", err)
+	case !isLocalHost(host):
+		return fmt.Errorf("ERROR: Cannot connect to PostgreSQL at %s: %v\nIt isn't on this machine, so gator can't start it. Check that the server is up and that db_url points at it", host, err)
+	default:
+		fmt.Println("PostgreSQL is not running:", err)
+		err = startPostgres(adminDSN, confirm)
 		if err != nil {
-			fmt.Println("CRITICAL ERROR: PostgreSQL failed to start even after initialization:", err)
-			os.Exit(1)
+			return err
 		}
+		fmt.Println("PostgreSQL is now running!")
+	}
+
+	err = ensureDatabaseExists(adminDSN, name, confirm)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("PostgreSQL is now running!")
+	// connect to gator db and apply migrations
+	dbGator, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return fmt.Errorf("ERROR: Failed to connect to '%s' database: %w", name, err)
+	}
+	defer dbGator.Close()
+
+	return Migrate(dbGator)
 }
 
-// Initialize postgres server if needed
-func initializePostgres() error {
-	cmd := exec.Command("sudo", "-u", "postgres", "initdb", "-D", "/var/lib/postgresql/data")
-	output, err := cmd.CombinedOutput()
+// Starts the local server, offering to initialize a data directory if it won't start
+func startPostgres(adminDSN string, confirm func(string) bool) error {
+	cmd, err := startCommand()
 	if err != nil {
-		return fmt.Errorf("failed to initialize PostgreSQL: %s - %s", err, string(output))
+		return err
 	}
-	return nil
+
+	fmt.Println("Gator can start it with:")
+	err = runConfirmed(cmd, "Start PostgreSQL?", confirm)
+	if errors.Is(err, ErrAborted) {
+		return err
+	}
+	if err == nil && pingPostgres(adminDSN) == nil {
+		return nil
+	}
+	if err != nil {
+		fmt.Println("Failed to start PostgreSQL:", err)
+	} else {
+		fmt.Println("PostgreSQL was started but still doesn't answer.")
+	}
+
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("ERROR: PostgreSQL won't start, check its logs")
+	}
+
+	// Only safe on a machine where PostgreSQL was installed but never set up
+	fmt.Println("If PostgreSQL was installed but never set up, it needs a data directory first.")
+	fmt.Println("Don't do this if this machine already has PostgreSQL data you care about. Gator can create one with:")
+	err = runConfirmed(initCommand(), "Initialize a new PostgreSQL data directory?", confirm)
+	if err != nil {
+		if errors.Is(err, ErrAborted) {
+			return err
+		}
+		return fmt.Errorf("ERROR: Failed to initialize PostgreSQL: %w", err)
+	}
+
+	// Retry starting PostgreSQL after initialization
+	cmd, _ = startCommand()
+	err = runConfirmed(cmd, "Start PostgreSQL?", confirm)
+	if err != nil {
+		if errors.Is(err, ErrAborted) {
+			return err
+		}
+		return fmt.Errorf("ERROR: PostgreSQL failed to start even after initialization: %w", err)
+	}
+
+	return pingPostgres(adminDSN)
 }
 
 // Create database if no database exists
-func EnsureDatabaseExists() error {
-
-	// Connect to the default "postgres" database to check for 'gator'
-	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=postgres sslmode=disable"
-	db, err := sql.Open("postgres", dsn)
+func ensureDatabaseExists(adminDSN string, name string, confirm func(string) bool) error {
+	// Connect to the maintenance database to check for gator's
+	db, err := sql.Open("postgres", adminDSN)
 	if err != nil {
 		return fmt.Errorf("ERROR: Cannot connect to PostgreSQL: %w", err)
 	}
@@ -134,86 +337,23 @@ func EnsureDatabaseExists() error {
 
 	// Check if gator db exists
 	var exists bool
-	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = 'gator')").Scan(&exists)
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("ERROR: Failed to check database existence: %w", err)
 	}
+	if exists {
+		fmt.Printf("Database %s already exists.\n", name)
+		return nil
+	}
 
 	// If the database does not exist, create it
-	if !exists {
-		_, err = db.Exec("CREATE DATABASE gator")
-		if err != nil {
-			return fmt.Errorf("ERROR: Failed to create database: %w", err)
-		}
+	if !confirm(fmt.Sprintf("Create database %s?", name)) {
+		return ErrAborted
 	}
-
-	// connect to gator db and apply migrations
-	gatorDSN := "host=localhost port=5432 user=postgres password=postgres dbname=gator sslmode=disable"
-	dbGator, err := sql.Open("postgres", gatorDSN)
+	_, err = db.Exec("CREATE DATABASE " + pq.QuoteIdentifier(name))
 	if err != nil {
-		return fmt.Errorf("ERROR: Failed to connect to 'gator' database: %w", err)
+		return fmt.Errorf("ERROR: Failed to create database: %w", err)
 	}
-	defer dbGator.Close()
-
-	return applyMigrations(dbGator)
-}
-
-// executes the schema creation SQL in order
-func applyMigrations(db *sql.DB) error {
-	fmt.Println("Verifying database integrity...")
-
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-		    id UUID PRIMARY KEY,
-		    created_at TIMESTAMP NOT NULL,
-		    updated_at TIMESTAMP NOT NULL,
-		    name TEXT UNIQUE NOT NULL
-		);`,
-
-		`CREATE TABLE IF NOT EXISTS feeds (
-		    id UUID PRIMARY KEY,
-		    created_at TIMESTAMP NOT NULL,
-		    updated_at TIMESTAMP NOT NULL,
-		    name TEXT NOT NULL,
-		    url TEXT UNIQUE NOT NULL,
-		    user_id UUID NOT NULL,
-		    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
-
-		`CREATE TABLE IF NOT EXISTS feed_follows (
-		    id UUID PRIMARY KEY,
-		    created_at TIMESTAMP NOT NULL,
-		    updated_at TIMESTAMP NOT NULL,
-		    user_id UUID NOT NULL,
-		    feed_id UUID NOT NULL,
-		    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
-		    UNIQUE (user_id, feed_id)
-		);`,
-
-		`ALTER TABLE feeds
-		ADD COLUMN IF NOT EXISTS last_fetched_at TIMESTAMP;`,
-
-		`CREATE TABLE IF NOT EXISTS posts (
-		    id UUID PRIMARY KEY,
-		    created_at TIMESTAMP NOT NULL,
-		    updated_at TIMESTAMP NOT NULL,
-		    title TEXT NOT NULL,
-		    url TEXT NOT NULL UNIQUE,
-		    description TEXT NOT NULL,
-		    published_at TEXT NOT NULL,
-		    feed_id UUID NOT NULL,
-		    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
-		);`,
-	}
-
-	for i, migration := range migrations {
-		_, err := db.Exec(migration)
-		if err != nil {
-			return fmt.Errorf("failed to execute update %d: %w", i+1, err)
-		}
-	}
-
-	fmt.Println("Database integrity verified!")
+	fmt.Printf("Created database %s.\n", name)
 	return nil
 }
@@ -0,0 +1,166 @@
+// Package dbtest has the stores and rows that the tests in every package start from
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// When the users and feeds made here were created
+var Time = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// A store to run tests against. Each call to Open returns an empty, migrated store.
+type Backend struct {
+	Name string
+	Open func(t *testing.T) database.Store
+}
+
+// The memory store and a SQLite file always, and PostgreSQL when GATOR_TEST_PG_URL points at a
+// database the tests may empty. There is only the one PostgreSQL database, so opening it again
+// empties the store opened before.
+func Backends(t *testing.T) []Backend {
+	backends := []Backend{
+		{"memory", func(t *testing.T) database.Store { return database.NewMemoryStore() }},
+		{"sqlite", func(t *testing.T) database.Store {
+			return open(t, "sqlite://"+filepath.Join(t.TempDir(), "gator.db"))
+		}},
+	}
+
+	if dsn := os.Getenv("GATOR_TEST_PG_URL"); dsn != "" {
+		backends = append(backends, Backend{"postgres", func(t *testing.T) database.Store {
+			store := open(t, dsn)
+			// Every other table hangs off users or feeds, so this empties the database
+			if err := store.ResetUsers(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.DeleteAllFeeds(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return store
+		}})
+	} else {
+		t.Log("GATOR_TEST_PG_URL isn't set, skipping PostgreSQL")
+	}
+	return backends
+}
+
+func open(t *testing.T, dbURL string) database.Store {
+	t.Helper()
+	db, err := database.Connect(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return database.NewStore(db)
+}
+
+func CreateUser(t *testing.T, s database.Store, name string, admin bool) database.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: Time,
+		UpdatedAt: Time,
+		Name:      name,
+		IsAdmin:   admin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// Adds a feed without following it
+func CreateFeed(t *testing.T, s database.Store, user database.User, name, url string) database.Feed {
+	t.Helper()
+	feed, err := s.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: Time,
+		UpdatedAt: Time,
+		Name:      name,
+		Url:       url,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func Follow(t *testing.T, s database.Store, user database.User, feed database.Feed) {
+	t.Helper()
+	_, err := s.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: Time,
+		UpdatedAt: Time,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A post for CreatePost. Its url is the feed's with the title on the end, so titles need to
+// differ within a feed.
+type Post struct {
+	// A new id when left out
+	ID    uuid.UUID
+	Title string
+	// Published at 09:00 UTC on this day of October 2026
+	Day int
+	// Published then instead, when set
+	PublishedAt time.Time
+	// Fetched when it was published, unless set
+	FetchedAt  time.Time
+	Authors    []string
+	Categories []string
+}
+
+func CreatePost(t *testing.T, s database.Store, feed database.Feed, p Post) database.Post {
+	t.Helper()
+	ctx := context.Background()
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	if p.PublishedAt.IsZero() {
+		p.PublishedAt = time.Date(2026, 10, p.Day, 9, 0, 0, 0, time.UTC)
+	}
+	if p.FetchedAt.IsZero() {
+		p.FetchedAt = p.PublishedAt
+	}
+
+	post, err := s.CreatePost(ctx, database.CreatePostParams{
+		ID:          p.ID,
+		CreatedAt:   p.FetchedAt,
+		UpdatedAt:   p.FetchedAt,
+		Title:       p.Title,
+		Url:         feed.Url + "/" + strings.ReplaceAll(strings.ToLower(p.Title), " ", "-"),
+		Description: "About " + p.Title,
+		PublishedAt: p.PublishedAt.Format(time.RFC3339),
+		FeedID:      feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, author := range p.Authors {
+		if err := s.CreatePostAuthor(ctx, database.CreatePostAuthorParams{PostID: post.ID, Name: author}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, category := range p.Categories {
+		if err := s.CreatePostCategory(ctx, database.CreatePostCategoryParams{PostID: post.ID, Name: category}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return post
+}
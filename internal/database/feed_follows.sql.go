@@ -7,29 +7,25 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
-WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
-    VALUES (
-        $1,
-        $2,
-        $3,
-        $4,
-        $5
-    )
-    RETURNING id, created_at, updated_at, user_id, feed_id)
-
-SELECT inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, users.name AS user_name, feeds.name AS feed_name
-FROM inserted_feed_follow
-INNER JOIN feeds
-ON inserted_feed_follow.feed_id = feeds.id
-INNER JOIN users
-ON inserted_feed_follow.user_id = users.id
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, notified_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $2
+)
+RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title, notified_at,
+    (SELECT users.name FROM users WHERE users.id = feed_follows.user_id) AS user_name,
+    (SELECT feeds.name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name
 `
 
 type CreateFeedFollowParams struct {
@@ -41,13 +37,16 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	UserName  string
-	FeedName  string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      string
+	NotifiedAt time.Time
+	UserName   string
+	FeedName   string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -65,13 +64,60 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.NotifiedAt,
 		&i.UserName,
 		&i.FeedName,
 	)
 	return i, err
 }
 
-const deleteFollow = `-- name: DeleteFollow :exec
+const markFollowsNotified = `-- name: MarkFollowsNotified :exec
+UPDATE feed_follows SET
+    notified_at = $2
+WHERE user_id = $1
+`
+
+type MarkFollowsNotifiedParams struct {
+	UserID     uuid.UUID
+	NotifiedAt time.Time
+}
+
+func (q *Queries) MarkFollowsNotified(ctx context.Context, arg MarkFollowsNotifiedParams) error {
+	_, err := q.db.ExecContext(ctx, markFollowsNotified, arg.UserID, arg.NotifiedAt)
+	return err
+}
+
+const setFollowTitle = `-- name: SetFollowTitle :execrows
+UPDATE feed_follows SET
+    title = $3,
+    updated_at = $4
+WHERE user_id = $1
+AND feed_id = $2
+`
+
+type SetFollowTitleParams struct {
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	Title     string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFollowTitle(ctx context.Context, arg SetFollowTitleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFollowTitle,
+		arg.UserID,
+		arg.FeedID,
+		arg.Title,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFollow = `-- name: DeleteFollow :execrows
 DELETE FROM feed_follows
 WHERE feed_follows.feed_id = (
     SELECT feeds.id
@@ -90,29 +136,43 @@ type DeleteFollowParams struct {
 	Url  string
 }
 
-func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) error {
-	_, err := q.db.ExecContext(ctx, deleteFollow, arg.Name, arg.Url)
-	return err
+func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFollow, arg.Name, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, feed_follows.notified_at, users.name AS user_name, COALESCE(NULLIF(feed_follows.title, ''), feeds.name) AS feed_name, feeds.url AS feed_url,
+    feeds.active AS feed_active, feeds.deactivated_at AS feed_deactivated_at, COALESCE(folders.name, '')::TEXT AS folder_name
 FROM feed_follows
 INNER JOIN users
 ON feed_follows.user_id = users.id
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders
+ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS LAST, feed_name
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	UserName  string
-	FeedName  string
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	UserID            uuid.UUID
+	FeedID            uuid.UUID
+	FolderID          uuid.NullUUID
+	Title             string
+	NotifiedAt        time.Time
+	UserName          string
+	FeedName          string
+	FeedUrl           string
+	FeedActive        bool
+	FeedDeactivatedAt sql.NullTime
+	FolderName        string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -130,8 +190,15 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
+			&i.NotifiedAt,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedActive,
+			&i.FeedDeactivatedAt,
+			&i.FolderName,
 		); err != nil {
 			return nil, err
 		}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,7 +24,7 @@ VALUES (
     $6,
 	$7
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, active, deactivated_at, retention_days, retention_posts
 `
 
 type CreateFeedParams struct {
@@ -55,10 +56,25 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.Active,
+		&i.DeactivatedAt,
+		&i.RetentionDays,
+		&i.RetentionPosts,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const getCreator = `-- name: GetCreator :one
 SELECT name FROM users
 WHERE users.id = $1
@@ -71,11 +87,37 @@ func (q *Queries) GetCreator(ctx context.Context, id uuid.UUID) (string, error)
 	return name, err
 }
 
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, active, deactivated_at, retention_days, retention_posts FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.Active,
+		&i.DeactivatedAt,
+		&i.RetentionDays,
+		&i.RetentionPosts,
+	)
+	return i, err
+}
+
 const getFeeds = `-- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds
+SELECT id, name, url, user_id FROM feeds
 `
 
 type GetFeedsRow struct {
+	ID     uuid.UUID
 	Name   string
 	Url    string
 	UserID uuid.UUID
@@ -90,7 +132,12 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.UserID); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -104,9 +151,213 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
+const getFeedsByNameOrIDPrefix = `-- name: GetFeedsByNameOrIDPrefix :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, active, deactivated_at, retention_days, retention_posts FROM feeds
+WHERE lower(name) = lower($1::TEXT)
+OR ($2::TEXT <> '' AND id::TEXT LIKE $2::TEXT || '%')
+ORDER BY lower(name) = lower($1::TEXT) DESC, name
+LIMIT 10
+`
+
+type GetFeedsByNameOrIDPrefixParams struct {
+	Name     string
+	IDPrefix string
+}
+
+// Name matches come first so the limit can't hide them behind feeds whose ids happen to match
+func (q *Queries) GetFeedsByNameOrIDPrefix(ctx context.Context, arg GetFeedsByNameOrIDPrefixParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByNameOrIDPrefix, arg.Name, arg.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullText,
+			&i.Active,
+			&i.DeactivatedAt,
+			&i.RetentionDays,
+			&i.RetentionPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :execrows
+UPDATE feed_follows SET
+    feed_id = $1,
+    updated_at = $2
+WHERE feed_id = $3
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows
+    WHERE feed_id = $1
+)
+`
+
+type MoveFeedFollowsParams struct {
+	NewFeedID uuid.UUID
+	UpdatedAt time.Time
+	OldFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedFollows, arg.NewFeedID, arg.UpdatedAt, arg.OldFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveFeedPosts = `-- name: MoveFeedPosts :execrows
+UPDATE posts SET
+    feed_id = $1,
+    updated_at = $2
+WHERE feed_id = $3
+`
+
+type MoveFeedPostsParams struct {
+	NewFeedID uuid.UUID
+	UpdatedAt time.Time
+	OldFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedPosts, arg.NewFeedID, arg.UpdatedAt, arg.OldFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedActive = `-- name: SetFeedActive :exec
+UPDATE feeds SET
+    active = $2,
+    deactivated_at = $3,
+    updated_at = $4
+WHERE id = $1
+`
+
+type SetFeedActiveParams struct {
+	ID            uuid.UUID
+	Active        bool
+	DeactivatedAt sql.NullTime
+	UpdatedAt     time.Time
+}
+
+func (q *Queries) SetFeedActive(ctx context.Context, arg SetFeedActiveParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedActive,
+		arg.ID,
+		arg.Active,
+		arg.DeactivatedAt,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const setFeedFullText = `-- name: SetFeedFullText :exec
+UPDATE feeds SET
+    fetch_full_text = $2,
+    updated_at = $3
+WHERE url = $1
+`
+
+type SetFeedFullTextParams struct {
+	Url           string
+	FetchFullText bool
+	UpdatedAt     time.Time
+}
+
+func (q *Queries) SetFeedFullText(ctx context.Context, arg SetFeedFullTextParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFullText, arg.Url, arg.FetchFullText, arg.UpdatedAt)
+	return err
+}
+
+const setFeedURL = `-- name: SetFeedURL :exec
+UPDATE feeds SET
+    url = $2,
+    updated_at = $3
+WHERE id = $1
+`
+
+type SetFeedURLParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFeedURL(ctx context.Context, arg SetFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedURL, arg.ID, arg.Url, arg.UpdatedAt)
+	return err
+}
+
+const updateFeed = `-- name: UpdateFeed :one
+UPDATE feeds SET
+    name = $2,
+    url = $3,
+    updated_at = $4
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, active, deactivated_at, retention_days, retention_posts
+`
+
+type UpdateFeedParams struct {
+	ID        uuid.UUID
+	Name      string
+	Url       string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateFeed(ctx context.Context, arg UpdateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeed,
+		arg.ID,
+		arg.Name,
+		arg.Url,
+		arg.UpdatedAt,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.Active,
+		&i.DeactivatedAt,
+		&i.RetentionDays,
+		&i.RetentionPosts,
+	)
+	return i, err
+}
+
 const uRLLookup = `-- name: URLLookup :one
 SELECT name, id FROM feeds
 WHERE url = $1
+OR id = (
+    SELECT feed_id FROM feed_url_history
+    WHERE feed_url_history.url = $1
+)
+ORDER BY url = $1 DESC
+LIMIT 1
 `
 
 type URLLookupRow struct {
@@ -120,3 +371,61 @@ func (q *Queries) URLLookup(ctx context.Context, url string) (URLLookupRow, erro
 	err := row.Scan(&i.Name, &i.ID)
 	return i, err
 }
+
+const deleteAllFeeds = `-- name: DeleteAllFeeds :execrows
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteAllFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds SET
+    retention_days = $2,
+    retention_posts = $3,
+    updated_at = $4
+WHERE id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID             uuid.UUID
+	RetentionDays  sql.NullInt32
+	RetentionPosts sql.NullInt32
+	UpdatedAt      time.Time
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention,
+		arg.ID,
+		arg.RetentionDays,
+		arg.RetentionPosts,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const reassignFeeds = `-- name: ReassignFeeds :execrows
+UPDATE feeds SET
+    user_id = $1,
+    updated_at = $2
+WHERE user_id = $3
+`
+
+type ReassignFeedsParams struct {
+	NewUserID uuid.UUID
+	UpdatedAt time.Time
+	OldUserID uuid.UUID
+}
+
+func (q *Queries) ReassignFeeds(ctx context.Context, arg ReassignFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reassignFeeds, arg.NewUserID, arg.UpdatedAt, arg.OldUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -13,20 +13,28 @@ import (
 )
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, url, name FROM feeds
+SELECT id, url, name, fetch_full_text FROM feeds
+WHERE active
 ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1
 `
 
 type GetNextFeedToFetchRow struct {
-	ID   uuid.UUID
-	Url  string
-	Name string
+	ID            uuid.UUID
+	Url           string
+	Name          string
+	FetchFullText bool
 }
 
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow, error) {
 	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
 	var i GetNextFeedToFetchRow
-	err := row.Scan(&i.ID, &i.Url, &i.Name)
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Name,
+		&i.FetchFullText,
+	)
 	return i, err
 }
 
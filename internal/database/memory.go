@@ -0,0 +1,1743 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stands in for the unique constraints the database would enforce
+var errDuplicate = errors.New("duplicate key value violates unique constraint")
+
+// Stands in for the foreign keys the database would enforce
+var errMissingReference = errors.New("insert or update violates foreign key constraint")
+
+type postStateKey struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+// A Store that keeps everything in maps. It follows the same rules as the queries do,
+// including the unique constraints and cascading deletes, so it can stand in for a real
+// database in the handler tests.
+type MemoryStore struct {
+	mu         sync.Mutex
+	txMu       sync.Mutex
+	users      map[uuid.UUID]User
+	feeds      map[uuid.UUID]Feed
+	follows    map[uuid.UUID]FeedFollow
+	folders    map[uuid.UUID]Folder
+	urlHistory map[string]FeedUrlHistory
+	posts      map[uuid.UUID]Post
+	authors    map[uuid.UUID][]string
+	categories map[uuid.UUID][]string
+	postStates map[postStateKey]PostState
+	pruned     map[string]PrunedPost
+	archived   map[uuid.UUID]ArchivedPost
+	apiTokens  map[uuid.UUID]ApiToken
+	sessions   map[uuid.UUID]WebSession
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	m := &MemoryStore{}
+	m.reset()
+	return m
+}
+
+func (m *MemoryStore) reset() {
+	m.users = map[uuid.UUID]User{}
+	m.feeds = map[uuid.UUID]Feed{}
+	m.follows = map[uuid.UUID]FeedFollow{}
+	m.folders = map[uuid.UUID]Folder{}
+	m.urlHistory = map[string]FeedUrlHistory{}
+	m.posts = map[uuid.UUID]Post{}
+	m.authors = map[uuid.UUID][]string{}
+	m.categories = map[uuid.UUID][]string{}
+	m.postStates = map[postStateKey]PostState{}
+	m.pruned = map[string]PrunedPost{}
+	m.archived = map[uuid.UUID]ArchivedPost{}
+	m.apiTokens = map[uuid.UUID]ApiToken{}
+	m.sessions = map[uuid.UUID]WebSession{}
+}
+
+// Copies everything, so a failed unit of work can be undone
+func (m *MemoryStore) snapshot() *MemoryStore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := &MemoryStore{
+		users:      maps.Clone(m.users),
+		feeds:      maps.Clone(m.feeds),
+		follows:    maps.Clone(m.follows),
+		folders:    maps.Clone(m.folders),
+		urlHistory: maps.Clone(m.urlHistory),
+		posts:      maps.Clone(m.posts),
+		authors:    map[uuid.UUID][]string{},
+		categories: map[uuid.UUID][]string{},
+		postStates: maps.Clone(m.postStates),
+		pruned:     maps.Clone(m.pruned),
+		archived:   maps.Clone(m.archived),
+		apiTokens:  maps.Clone(m.apiTokens),
+		sessions:   maps.Clone(m.sessions),
+	}
+	for id, names := range m.authors {
+		copied.authors[id] = slices.Clone(names)
+	}
+	for id, names := range m.categories {
+		copied.categories[id] = slices.Clone(names)
+	}
+	return copied
+}
+
+func (m *MemoryStore) restore(from *MemoryStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users = from.users
+	m.feeds = from.feeds
+	m.follows = from.follows
+	m.folders = from.folders
+	m.urlHistory = from.urlHistory
+	m.posts = from.posts
+	m.authors = from.authors
+	m.categories = from.categories
+	m.postStates = from.postStates
+	m.pruned = from.pruned
+	m.archived = from.archived
+	m.apiTokens = from.apiTokens
+	m.sessions = from.sessions
+}
+
+// Runs fn as one unit of work, putting everything back the way it was if fn fails.
+// Units of work run one at a time, but other calls still see their changes as they happen.
+func (m *MemoryStore) InTx(ctx context.Context, fn func(Store) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	before := m.snapshot()
+	committed := false
+	defer func() {
+		if !committed {
+			m.restore(before)
+		}
+	}()
+
+	err := fn(memoryTx{m})
+	if err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// The Store handed to a unit of work, nested units of work join the one already running
+type memoryTx struct {
+	*MemoryStore
+}
+
+func (tx memoryTx) InTx(ctx context.Context, fn func(Store) error) error {
+	return fn(tx)
+}
+
+// Map values in creation order, so results that the queries leave unordered still come out the same every time
+func sortedValues[K comparable, V any](values map[K]V, createdAt func(V) time.Time, id func(V) uuid.UUID) []V {
+	items := make([]V, 0, len(values))
+	for _, v := range values {
+		items = append(items, v)
+	}
+	slices.SortFunc(items, func(a, b V) int {
+		if c := createdAt(a).Compare(createdAt(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(id(a).String(), id(b).String())
+	})
+	return items
+}
+
+func (m *MemoryStore) sortedFeeds() []Feed {
+	return sortedValues(m.feeds, func(f Feed) time.Time { return f.CreatedAt }, func(f Feed) uuid.UUID { return f.ID })
+}
+
+func (m *MemoryStore) sortedFollows() []FeedFollow {
+	return sortedValues(m.follows, func(f FeedFollow) time.Time { return f.CreatedAt }, func(f FeedFollow) uuid.UUID { return f.ID })
+}
+
+func (m *MemoryStore) sortedPosts() []Post {
+	return sortedValues(m.posts, func(p Post) time.Time { return p.CreatedAt }, func(p Post) uuid.UUID { return p.ID })
+}
+
+// A follow's own title wins over the feed's name, as in the queries
+func followTitle(follow FeedFollow, feed Feed) string {
+	if follow.Title != "" {
+		return follow.Title
+	}
+	return feed.Name
+}
+
+// Mirrors ILIKE '%' || substr || '%'
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Users
+
+func (m *MemoryStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.ID == arg.ID || u.Name == arg.Name {
+			return User{}, fmt.Errorf("%w: users", errDuplicate)
+		}
+	}
+	user := User{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		IsAdmin:   arg.IsAdmin,
+	}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *MemoryStore) GetUser(ctx context.Context, name string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetUsers(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for _, u := range sortedValues(m.users, func(u User) time.Time { return u.CreatedAt }, func(u User) uuid.UUID { return u.ID }) {
+		names = append(names, u.Name)
+	}
+	return names, nil
+}
+
+func (m *MemoryStore) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var updated int64
+	for id, u := range m.users {
+		if u.Name == arg.Name {
+			u.IsAdmin = arg.IsAdmin
+			u.UpdatedAt = arg.UpdatedAt
+			m.users[id] = u
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (m *MemoryStore) DeleteUser(ctx context.Context, name string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, u := range m.users {
+		if u.Name != name {
+			continue
+		}
+		delete(m.users, id)
+		for feedID, f := range m.feeds {
+			if f.UserID == id {
+				m.deleteFeed(feedID)
+			}
+		}
+		for folderID, f := range m.folders {
+			if f.UserID == id {
+				delete(m.folders, folderID)
+			}
+		}
+		for followID, f := range m.follows {
+			if f.UserID == id {
+				delete(m.follows, followID)
+			}
+		}
+		for key := range m.postStates {
+			if key.UserID == id {
+				delete(m.postStates, key)
+			}
+		}
+		for tokenID, t := range m.apiTokens {
+			if t.UserID == id {
+				m.deleteAPIToken(tokenID)
+			}
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStore) ResetUsers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Everything else hangs off a user, so it all cascades away with them
+	m.reset()
+	return nil
+}
+
+// API tokens
+
+func (m *MemoryStore) CreateAPIToken(ctx context.Context, arg CreateAPITokenParams) (ApiToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ApiToken{}, fmt.Errorf("%w: api_tokens.user_id", errMissingReference)
+	}
+	for _, t := range m.apiTokens {
+		if t.ID == arg.ID || t.TokenHash == arg.TokenHash || (t.UserID == arg.UserID && t.Name == arg.Name) {
+			return ApiToken{}, fmt.Errorf("%w: api_tokens", errDuplicate)
+		}
+	}
+	token := ApiToken(arg)
+	m.apiTokens[token.ID] = token
+	return token, nil
+}
+
+func (m *MemoryStore) GetAPIToken(ctx context.Context, tokenHash string) (ApiToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.apiTokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return ApiToken{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetUserByAPIToken(ctx context.Context, tokenHash string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.apiTokens {
+		if t.TokenHash == tokenHash {
+			return m.users[t.UserID], nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetAPITokensForUser(ctx context.Context, userID uuid.UUID) ([]ApiToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []ApiToken
+	for _, t := range m.apiTokens {
+		if t.UserID == userID {
+			items = append(items, t)
+		}
+	}
+	slices.SortFunc(items, func(a, b ApiToken) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) DeleteAPIToken(ctx context.Context, arg DeleteAPITokenParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, t := range m.apiTokens {
+		if t.UserID == arg.UserID && t.Name == arg.Name {
+			m.deleteAPIToken(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Deletes a token along with the web sessions started with it, m.mu must be held
+func (m *MemoryStore) deleteAPIToken(id uuid.UUID) {
+	delete(m.apiTokens, id)
+	for sessionID, session := range m.sessions {
+		if session.ApiTokenID == id {
+			delete(m.sessions, sessionID)
+		}
+	}
+}
+
+// Web sessions
+
+func (m *MemoryStore) CreateWebSession(ctx context.Context, arg CreateWebSessionParams) (WebSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.apiTokens[arg.ApiTokenID]; !ok {
+		return WebSession{}, fmt.Errorf("%w: web_sessions.api_token_id", errMissingReference)
+	}
+	for _, session := range m.sessions {
+		if session.ID == arg.ID || session.TokenHash == arg.TokenHash {
+			return WebSession{}, fmt.Errorf("%w: web_sessions", errDuplicate)
+		}
+	}
+	session := WebSession(arg)
+	m.sessions[session.ID] = session
+	return session, nil
+}
+
+func (m *MemoryStore) GetUserByWebSession(ctx context.Context, arg GetUserByWebSessionParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, session := range m.sessions {
+		if session.TokenHash == arg.TokenHash && session.ExpiresAt.After(arg.ExpiresAt) {
+			return m.users[m.apiTokens[session.ApiTokenID].UserID], nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) DeleteWebSession(ctx context.Context, tokenHash string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, session := range m.sessions {
+		if session.TokenHash == tokenHash {
+			delete(m.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStore) DeleteExpiredWebSessions(ctx context.Context, expiresAt time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, session := range m.sessions {
+		if !session.ExpiresAt.After(expiresAt) {
+			delete(m.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Feeds
+
+func (m *MemoryStore) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Feed{}, fmt.Errorf("%w: feeds.user_id", errMissingReference)
+	}
+	for _, f := range m.feeds {
+		if f.ID == arg.ID || f.Url == arg.Url {
+			return Feed{}, fmt.Errorf("%w: feeds", errDuplicate)
+		}
+	}
+	feed := Feed{
+		ID:            arg.ID,
+		CreatedAt:     arg.CreatedAt,
+		UpdatedAt:     arg.UpdatedAt,
+		Name:          arg.Name,
+		Url:           arg.Url,
+		UserID:        arg.UserID,
+		LastFetchedAt: arg.LastFetchedAt,
+		Active:        true,
+	}
+	m.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (m *MemoryStore) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteFeed(id)
+	return nil
+}
+
+func (m *MemoryStore) DeleteAllFeeds(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := int64(len(m.feeds))
+	for id := range m.feeds {
+		m.deleteFeed(id)
+	}
+	return deleted, nil
+}
+
+// Removes a feed along with everything its foreign keys cascade to
+func (m *MemoryStore) deleteFeed(id uuid.UUID) {
+	delete(m.feeds, id)
+	for followID, f := range m.follows {
+		if f.FeedID == id {
+			delete(m.follows, followID)
+		}
+	}
+	for url, h := range m.urlHistory {
+		if h.FeedID == id {
+			delete(m.urlHistory, url)
+		}
+	}
+	for postID, p := range m.posts {
+		if p.FeedID == id {
+			m.deletePost(postID)
+		}
+	}
+	for url, p := range m.pruned {
+		if p.FeedID == id {
+			delete(m.pruned, url)
+		}
+	}
+	for postID, p := range m.archived {
+		if p.FeedID == id {
+			delete(m.archived, postID)
+		}
+	}
+}
+
+func (m *MemoryStore) deletePost(id uuid.UUID) {
+	delete(m.posts, id)
+	delete(m.authors, id)
+	delete(m.categories, id)
+	for key := range m.postStates {
+		if key.PostID == id {
+			delete(m.postStates, key)
+		}
+	}
+}
+
+func (m *MemoryStore) GetCreator(ctx context.Context, id uuid.UUID) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return user.Name, nil
+}
+
+func (m *MemoryStore) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed, ok := m.feeds[id]
+	if !ok {
+		return Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (m *MemoryStore) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []GetFeedsRow
+	for _, f := range m.sortedFeeds() {
+		items = append(items, GetFeedsRow{
+			ID:     f.ID,
+			Name:   f.Name,
+			Url:    f.Url,
+			UserID: f.UserID,
+		})
+	}
+	return items, nil
+}
+
+func (m *MemoryStore) GetFeedsByNameOrIDPrefix(ctx context.Context, arg GetFeedsByNameOrIDPrefixParams) ([]Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []Feed
+	for _, f := range m.sortedFeeds() {
+		if strings.EqualFold(f.Name, arg.Name) || (arg.IDPrefix != "" && strings.HasPrefix(f.ID.String(), arg.IDPrefix)) {
+			items = append(items, f)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b Feed) int {
+		aNamed, bNamed := strings.EqualFold(a.Name, arg.Name), strings.EqualFold(b.Name, arg.Name)
+		if aNamed != bNamed {
+			if aNamed {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(items) > 10 {
+		items = items[:10]
+	}
+	return items, nil
+}
+
+func (m *MemoryStore) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	following := map[uuid.UUID]bool{}
+	for _, f := range m.follows {
+		if f.FeedID == arg.NewFeedID {
+			following[f.UserID] = true
+		}
+	}
+
+	var moved int64
+	for id, f := range m.follows {
+		if f.FeedID == arg.OldFeedID && !following[f.UserID] {
+			f.FeedID = arg.NewFeedID
+			f.UpdatedAt = arg.UpdatedAt
+			m.follows[id] = f
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (m *MemoryStore) MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var moved int64
+	for id, p := range m.posts {
+		if p.FeedID == arg.OldFeedID {
+			p.FeedID = arg.NewFeedID
+			p.UpdatedAt = arg.UpdatedAt
+			m.posts[id] = p
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (m *MemoryStore) ReassignFeeds(ctx context.Context, arg ReassignFeedsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.NewUserID]; !ok {
+		return 0, fmt.Errorf("%w: feeds.user_id", errMissingReference)
+	}
+	var reassigned int64
+	for id, f := range m.feeds {
+		if f.UserID == arg.OldUserID {
+			f.UserID = arg.NewUserID
+			f.UpdatedAt = arg.UpdatedAt
+			m.feeds[id] = f
+			reassigned++
+		}
+	}
+	return reassigned, nil
+}
+
+func (m *MemoryStore) SetFeedActive(ctx context.Context, arg SetFeedActiveParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if feed, ok := m.feeds[arg.ID]; ok {
+		feed.Active = arg.Active
+		feed.DeactivatedAt = arg.DeactivatedAt
+		feed.UpdatedAt = arg.UpdatedAt
+		m.feeds[arg.ID] = feed
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetFeedFullText(ctx context.Context, arg SetFeedFullTextParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, f := range m.feeds {
+		if f.Url == arg.Url {
+			f.FetchFullText = arg.FetchFullText
+			f.UpdatedAt = arg.UpdatedAt
+			m.feeds[id] = f
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetFeedURL(ctx context.Context, arg SetFeedURLParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.setFeedURL(arg.ID, arg.Url, arg.UpdatedAt)
+}
+
+func (m *MemoryStore) setFeedURL(id uuid.UUID, url string, updatedAt time.Time) error {
+	feed, ok := m.feeds[id]
+	if !ok {
+		return nil
+	}
+	for _, f := range m.feeds {
+		if f.ID != id && f.Url == url {
+			return fmt.Errorf("%w: feeds.url", errDuplicate)
+		}
+	}
+	feed.Url = url
+	feed.UpdatedAt = updatedAt
+	m.feeds[id] = feed
+	return nil
+}
+
+func (m *MemoryStore) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if feed, ok := m.feeds[arg.ID]; ok {
+		feed.RetentionDays = arg.RetentionDays
+		feed.RetentionPosts = arg.RetentionPosts
+		feed.UpdatedAt = arg.UpdatedAt
+		m.feeds[arg.ID] = feed
+	}
+	return nil
+}
+
+func (m *MemoryStore) UpdateFeed(ctx context.Context, arg UpdateFeedParams) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed, ok := m.feeds[arg.ID]
+	if !ok {
+		return Feed{}, sql.ErrNoRows
+	}
+	err := m.setFeedURL(arg.ID, arg.Url, arg.UpdatedAt)
+	if err != nil {
+		return Feed{}, err
+	}
+	feed = m.feeds[arg.ID]
+	feed.Name = arg.Name
+	m.feeds[arg.ID] = feed
+	return feed, nil
+}
+
+func (m *MemoryStore) URLLookup(ctx context.Context, url string) (URLLookupRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A feed's current url wins over one it has moved away from
+	for _, f := range m.feeds {
+		if f.Url == url {
+			return URLLookupRow{Name: f.Name, ID: f.ID}, nil
+		}
+	}
+	if h, ok := m.urlHistory[url]; ok {
+		if f, ok := m.feeds[h.FeedID]; ok {
+			return URLLookupRow{Name: f.Name, ID: f.ID}, nil
+		}
+	}
+	return URLLookupRow{}, sql.ErrNoRows
+}
+
+// Follows
+
+func (m *MemoryStore) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.UserID]
+	if !ok {
+		return CreateFeedFollowRow{}, fmt.Errorf("%w: feed_follows.user_id", errMissingReference)
+	}
+	feed, ok := m.feeds[arg.FeedID]
+	if !ok {
+		return CreateFeedFollowRow{}, fmt.Errorf("%w: feed_follows.feed_id", errMissingReference)
+	}
+	for _, f := range m.follows {
+		if f.ID == arg.ID || (f.UserID == arg.UserID && f.FeedID == arg.FeedID) {
+			return CreateFeedFollowRow{}, fmt.Errorf("%w: feed_follows", errDuplicate)
+		}
+	}
+
+	follow := FeedFollow{
+		ID:         arg.ID,
+		CreatedAt:  arg.CreatedAt,
+		UpdatedAt:  arg.UpdatedAt,
+		UserID:     arg.UserID,
+		FeedID:     arg.FeedID,
+		NotifiedAt: arg.CreatedAt,
+	}
+	m.follows[follow.ID] = follow
+
+	return CreateFeedFollowRow{
+		ID:         follow.ID,
+		CreatedAt:  follow.CreatedAt,
+		UpdatedAt:  follow.UpdatedAt,
+		UserID:     follow.UserID,
+		FeedID:     follow.FeedID,
+		FolderID:   follow.FolderID,
+		Title:      follow.Title,
+		NotifiedAt: follow.NotifiedAt,
+		UserName:   user.Name,
+		FeedName:   feed.Name,
+	}, nil
+}
+
+func (m *MemoryStore) MarkFollowsNotified(ctx context.Context, arg MarkFollowsNotifiedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, f := range m.follows {
+		if f.UserID == arg.UserID {
+			f.NotifiedAt = arg.NotifiedAt
+			m.follows[id] = f
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetFollowTitle(ctx context.Context, arg SetFollowTitleParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var updated int64
+	for id, f := range m.follows {
+		if f.UserID == arg.UserID && f.FeedID == arg.FeedID {
+			f.Title = arg.Title
+			f.UpdatedAt = arg.UpdatedAt
+			m.follows[id] = f
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (m *MemoryStore) DeleteFollow(ctx context.Context, arg DeleteFollowParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, f := range m.follows {
+		if m.users[f.UserID].Name == arg.Name && m.feeds[f.FeedID].Url == arg.Url {
+			delete(m.follows, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStore) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []GetFeedFollowsForUserRow
+	for _, f := range m.sortedFollows() {
+		if f.UserID != userID {
+			continue
+		}
+		feed := m.feeds[f.FeedID]
+		folderName := ""
+		if f.FolderID.Valid {
+			folderName = m.folders[f.FolderID.UUID].Name
+		}
+		items = append(items, GetFeedFollowsForUserRow{
+			ID:                f.ID,
+			CreatedAt:         f.CreatedAt,
+			UpdatedAt:         f.UpdatedAt,
+			UserID:            f.UserID,
+			FeedID:            f.FeedID,
+			FolderID:          f.FolderID,
+			Title:             f.Title,
+			NotifiedAt:        f.NotifiedAt,
+			UserName:          m.users[f.UserID].Name,
+			FeedName:          followTitle(f, feed),
+			FeedUrl:           feed.Url,
+			FeedActive:        feed.Active,
+			FeedDeactivatedAt: feed.DeactivatedAt,
+			FolderName:        folderName,
+		})
+	}
+
+	// Folders by name with unfiled feeds last, then feeds by name
+	slices.SortStableFunc(items, func(a, b GetFeedFollowsForUserRow) int {
+		if a.FolderID.Valid != b.FolderID.Valid {
+			if a.FolderID.Valid {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(a.FolderName, b.FolderName); c != 0 {
+			return c
+		}
+		return strings.Compare(a.FeedName, b.FeedName)
+	})
+	return items, nil
+}
+
+// Folders
+
+func (m *MemoryStore) CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Folder{}, fmt.Errorf("%w: folders.user_id", errMissingReference)
+	}
+	for _, f := range m.folders {
+		if f.ID == arg.ID || (f.UserID == arg.UserID && f.Name == arg.Name) {
+			return Folder{}, fmt.Errorf("%w: folders", errDuplicate)
+		}
+	}
+	folder := Folder{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+	}
+	m.folders[folder.ID] = folder
+	return folder, nil
+}
+
+func (m *MemoryStore) DeleteFolder(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.folders, id)
+	// ON DELETE SET NULL
+	for followID, f := range m.follows {
+		if f.FolderID.Valid && f.FolderID.UUID == id {
+			f.FolderID = uuid.NullUUID{}
+			m.follows[followID] = f
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.folders {
+		if f.UserID == arg.UserID && strings.EqualFold(f.Name, arg.Lower) {
+			return f, nil
+		}
+	}
+	return Folder{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []Folder
+	for _, f := range m.folders {
+		if f.UserID == userID {
+			items = append(items, f)
+		}
+	}
+	slices.SortFunc(items, func(a, b Folder) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+func (m *MemoryStore) SetFollowFolder(ctx context.Context, arg SetFollowFolderParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.FolderID.Valid {
+		if _, ok := m.folders[arg.FolderID.UUID]; !ok {
+			return 0, fmt.Errorf("%w: feed_follows.folder_id", errMissingReference)
+		}
+	}
+
+	var updated int64
+	for id, f := range m.follows {
+		if f.UserID == arg.UserID && f.FeedID == arg.FeedID {
+			f.FolderID = arg.FolderID
+			f.UpdatedAt = arg.UpdatedAt
+			m.follows[id] = f
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// Feed url history
+
+func (m *MemoryStore) AddFeedURLHistory(ctx context.Context, arg AddFeedURLHistoryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feeds[arg.FeedID]; !ok {
+		return fmt.Errorf("%w: feed_url_history.feed_id", errMissingReference)
+	}
+	m.urlHistory[arg.Url] = FeedUrlHistory{
+		Url:     arg.Url,
+		FeedID:  arg.FeedID,
+		MovedAt: arg.MovedAt,
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteFeedURLHistory(ctx context.Context, url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.urlHistory, url)
+	return nil
+}
+
+func (m *MemoryStore) GetFeedMovesForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedMovesForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []GetFeedMovesForUserRow
+	for _, f := range m.sortedFollows() {
+		if f.UserID != userID {
+			continue
+		}
+		feed := m.feeds[f.FeedID]
+		for _, h := range m.urlHistory {
+			if h.FeedID == f.FeedID && h.MovedAt.After(f.NotifiedAt) {
+				items = append(items, GetFeedMovesForUserRow{
+					FeedName: followTitle(f, feed),
+					OldUrl:   h.Url,
+					NewUrl:   feed.Url,
+					MovedAt:  h.MovedAt,
+				})
+			}
+		}
+	}
+	slices.SortStableFunc(items, func(a, b GetFeedMovesForUserRow) int {
+		if c := a.MovedAt.Compare(b.MovedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.OldUrl, b.OldUrl)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) MoveFeedURLHistory(ctx context.Context, arg MoveFeedURLHistoryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for url, h := range m.urlHistory {
+		if h.FeedID == arg.OldFeedID {
+			h.FeedID = arg.NewFeedID
+			m.urlHistory[url] = h
+		}
+	}
+	return nil
+}
+
+// Fetch scheduling
+
+func (m *MemoryStore) GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next *Feed
+	for _, f := range m.sortedFeeds() {
+		if f.Active && (next == nil || f.LastFetchedAt.Before(next.LastFetchedAt)) {
+			next = &f
+		}
+	}
+	if next == nil {
+		return GetNextFeedToFetchRow{}, sql.ErrNoRows
+	}
+	return GetNextFeedToFetchRow{
+		ID:            next.ID,
+		Url:           next.Url,
+		Name:          next.Name,
+		FetchFullText: next.FetchFullText,
+	}, nil
+}
+
+func (m *MemoryStore) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if feed, ok := m.feeds[arg.ID]; ok {
+		feed.LastFetchedAt = arg.LastFetchedAt
+		feed.UpdatedAt = arg.LastFetchedAt
+		m.feeds[arg.ID] = feed
+	}
+	return nil
+}
+
+// Posts
+
+func (m *MemoryStore) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feeds[arg.FeedID]; !ok {
+		return Post{}, fmt.Errorf("%w: posts.feed_id", errMissingReference)
+	}
+	for _, p := range m.posts {
+		if p.ID == arg.ID || p.Url == arg.Url {
+			return Post{}, fmt.Errorf("%w: posts", errDuplicate)
+		}
+	}
+	post := Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Content:     arg.Content,
+	}
+	m.posts[post.ID] = post
+	return post, nil
+}
+
+func (m *MemoryStore) DeleteAllPosts(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := int64(len(m.posts))
+	for id := range m.posts {
+		m.deletePost(id)
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStore) GetPostsByIDPrefix(ctx context.Context, arg GetPostsByIDPrefixParams) ([]GetPostsByIDPrefixRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	follows := m.followsByFeed(arg.UserID)
+	var items []GetPostsByIDPrefixRow
+	for _, p := range m.posts {
+		if _, ok := follows[p.FeedID]; ok && strings.HasPrefix(p.ID.String(), arg.Prefix) {
+			items = append(items, GetPostsByIDPrefixRow{ID: p.ID, Title: p.Title, Url: p.Url, PublishedAt: p.PublishedAt})
+		}
+	}
+	slices.SortFunc(items, func(a, b GetPostsByIDPrefixRow) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+	if len(items) > 10 {
+		items = items[:10]
+	}
+	return items, nil
+}
+
+func (m *MemoryStore) GetPostIDNeighbours(ctx context.Context, arg GetPostIDNeighboursParams) (GetPostIDNeighboursRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	follows := m.followsByFeed(arg.UserID)
+	id := arg.ID.String()
+	var row GetPostIDNeighboursRow
+	for _, p := range m.posts {
+		if _, ok := follows[p.FeedID]; !ok {
+			continue
+		}
+		other := p.ID.String()
+		if other < id && (!row.PreviousID.Valid || other > row.PreviousID.UUID.String()) {
+			row.PreviousID = uuid.NullUUID{UUID: p.ID, Valid: true}
+		}
+		if other > id && (!row.NextID.Valid || other < row.NextID.UUID.String()) {
+			row.NextID = uuid.NullUUID{UUID: p.ID, Valid: true}
+		}
+	}
+	return row, nil
+}
+
+func (m *MemoryStore) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if post, ok := m.posts[arg.ID]; ok {
+		post.Content = arg.Content
+		post.UpdatedAt = arg.UpdatedAt
+		m.posts[arg.ID] = post
+	}
+	return nil
+}
+
+func (m *MemoryStore) CreatePostAuthor(ctx context.Context, arg CreatePostAuthorParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.posts[arg.PostID]; !ok {
+		return fmt.Errorf("%w: post_authors.post_id", errMissingReference)
+	}
+	if !slices.Contains(m.authors[arg.PostID], arg.Name) {
+		m.authors[arg.PostID] = append(m.authors[arg.PostID], arg.Name)
+	}
+	return nil
+}
+
+func (m *MemoryStore) CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.posts[arg.PostID]; !ok {
+		return fmt.Errorf("%w: post_categories.post_id", errMissingReference)
+	}
+	if !slices.Contains(m.categories[arg.PostID], arg.Name) {
+		m.categories[arg.PostID] = append(m.categories[arg.PostID], arg.Name)
+	}
+	return nil
+}
+
+// Retention
+
+func (m *MemoryStore) GetPostsToPrune(ctx context.Context, arg GetPostsToPruneParams) ([]uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var posts []Post
+	for _, p := range m.posts {
+		if p.FeedID == arg.FeedID {
+			posts = append(posts, p)
+		}
+	}
+	slices.SortFunc(posts, func(a, b Post) int {
+		if c := byPublishedDesc(a.PublishedAt, b.PublishedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	var items []uuid.UUID
+	for i, p := range posts {
+		if arg.PublishedBefore.Valid && p.PublishedAt >= arg.PublishedBefore.String {
+			continue
+		}
+		if arg.KeepNewest.Valid && int64(i+1) <= arg.KeepNewest.Int64 {
+			continue
+		}
+		starred := false
+		for key, state := range m.postStates {
+			if key.PostID == p.ID && state.Starred {
+				starred = true
+				break
+			}
+		}
+		if !starred {
+			items = append(items, p.ID)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemoryStore) AddPrunedPost(ctx context.Context, arg AddPrunedPostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[arg.ID]
+	if !ok {
+		return nil
+	}
+	if _, ok := m.pruned[post.Url]; !ok {
+		m.pruned[post.Url] = PrunedPost{Url: post.Url, FeedID: post.FeedID, PrunedAt: arg.PrunedAt}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ArchivePost(ctx context.Context, arg ArchivePostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[arg.ID]
+	if !ok {
+		return nil
+	}
+	if _, ok := m.archived[post.ID]; !ok {
+		m.archived[post.ID] = archivedPost(post, arg.ArchivedAt)
+	}
+	return nil
+}
+
+func archivedPost(p Post, archivedAt time.Time) ArchivedPost {
+	return ArchivedPost{
+		ID:          p.ID,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		Title:       p.Title,
+		Url:         p.Url,
+		Description: p.Description,
+		PublishedAt: p.PublishedAt,
+		FeedID:      p.FeedID,
+		Content:     p.Content,
+		ArchivedAt:  archivedAt,
+	}
+}
+
+func (m *MemoryStore) DeletePost(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deletePost(id)
+	return nil
+}
+
+func (m *MemoryStore) IsPostPruned(ctx context.Context, url string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.pruned[url]
+	return ok, nil
+}
+
+func (m *MemoryStore) MoveArchivedPosts(ctx context.Context, arg MoveArchivedPostsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var moved int64
+	for id, p := range m.archived {
+		if p.FeedID == arg.OldFeedID {
+			p.FeedID = arg.NewFeedID
+			p.UpdatedAt = arg.UpdatedAt
+			m.archived[id] = p
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (m *MemoryStore) MovePrunedPosts(ctx context.Context, arg MovePrunedPostsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for url, p := range m.pruned {
+		if p.FeedID == arg.OldFeedID {
+			p.FeedID = arg.NewFeedID
+			m.pruned[url] = p
+		}
+	}
+	return nil
+}
+
+// Follows of userID, keyed by feed
+func (m *MemoryStore) followsByFeed(userID uuid.UUID) map[uuid.UUID]FeedFollow {
+	follows := map[uuid.UUID]FeedFollow{}
+	for _, f := range m.follows {
+		if f.UserID == userID {
+			follows[f.FeedID] = f
+		}
+	}
+	return follows
+}
+
+// Newest first, going by the feed's own publish date
+func byPublishedDesc(a, b string) int {
+	return cmp.Compare(b, a)
+}
+
+// Compares posts the way browse pages through them, by publish date and then id.
+// Without an id only the dates count, as comparing with a null does in the query.
+func comparePostKeys(publishedAt string, id uuid.UUID, otherPublishedAt string, otherID uuid.NullUUID) int {
+	if c := cmp.Compare(publishedAt, otherPublishedAt); c != 0 || !otherID.Valid {
+		return c
+	}
+	return strings.Compare(id.String(), otherID.UUID.String())
+}
+
+func (m *MemoryStore) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	follows := m.followsByFeed(arg.UserID)
+
+	var items []GetPostsForUserRow
+	for _, p := range m.sortedPosts() {
+		follow, ok := follows[p.FeedID]
+		if !ok {
+			continue
+		}
+		if arg.Folder != "" && (!follow.FolderID.Valid || !strings.EqualFold(m.folders[follow.FolderID.UUID].Name, arg.Folder)) {
+			continue
+		}
+		if arg.Author != "" && !slices.ContainsFunc(m.authors[p.ID], func(name string) bool { return containsFold(name, arg.Author) }) {
+			continue
+		}
+		if arg.Category != "" && !slices.ContainsFunc(m.categories[p.ID], func(name string) bool { return strings.EqualFold(name, arg.Category) }) {
+			continue
+		}
+		state := m.postStates[postStateKey{UserID: arg.UserID, PostID: p.ID}]
+		if arg.StarredOnly && !state.Starred {
+			continue
+		}
+		if arg.BeforePublishedAt.Valid && comparePostKeys(p.PublishedAt, p.ID, arg.BeforePublishedAt.String, arg.BeforeID) >= 0 {
+			continue
+		}
+		items = append(items, GetPostsForUserRow{
+			ID:          p.ID,
+			Title:       p.Title,
+			Description: p.Description,
+			Content:     p.Content,
+			Url:         p.Url,
+			PublishedAt: p.PublishedAt,
+			FeedTitle:   followTitle(follow, m.feeds[p.FeedID]),
+			Authors:     strings.Join(m.authors[p.ID], NameSeparator),
+			Categories:  strings.Join(m.categories[p.ID], NameSeparator),
+			Read:        state.Read,
+			Starred:     state.Starred,
+		})
+	}
+	slices.SortFunc(items, func(a, b GetPostsForUserRow) int {
+		return comparePostKeys(b.PublishedAt, b.ID, a.PublishedAt, uuid.NullUUID{UUID: a.ID, Valid: true})
+	})
+	if len(items) > int(arg.MaxPosts) {
+		items = items[:max(arg.MaxPosts, 0)]
+	}
+	return items, nil
+}
+
+// Read and starred state
+
+func (m *MemoryStore) GetFeedsWithUnreadCount(ctx context.Context, userID uuid.UUID) ([]GetFeedsWithUnreadCountRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unread := map[uuid.UUID]int64{}
+	for _, p := range m.posts {
+		if !m.postStates[postStateKey{UserID: userID, PostID: p.ID}].Read {
+			unread[p.FeedID]++
+		}
+	}
+
+	var items []GetFeedsWithUnreadCountRow
+	for _, f := range m.sortedFollows() {
+		if f.UserID != userID {
+			continue
+		}
+		feed := m.feeds[f.FeedID]
+		items = append(items, GetFeedsWithUnreadCountRow{
+			ID:            feed.ID,
+			Name:          followTitle(f, feed),
+			Url:           feed.Url,
+			FetchFullText: feed.FetchFullText,
+			Unread:        unread[feed.ID],
+		})
+	}
+	slices.SortStableFunc(items, func(a, b GetFeedsWithUnreadCountRow) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+func (m *MemoryStore) GetPostsWithState(ctx context.Context, arg GetPostsWithStateParams) ([]GetPostsWithStateRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	follows := m.followsByFeed(arg.UserID)
+
+	var items []GetPostsWithStateRow
+	for _, p := range m.sortedPosts() {
+		follow, ok := follows[p.FeedID]
+		if !ok {
+			continue
+		}
+		if arg.FeedID.Valid && p.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		state := m.postStates[postStateKey{UserID: arg.UserID, PostID: p.ID}]
+		if arg.StarredOnly && !state.Starred {
+			continue
+		}
+		if arg.Search != "" && !containsFold(p.Title, arg.Search) && !containsFold(p.Description, arg.Search) && !containsFold(p.Content, arg.Search) {
+			continue
+		}
+		items = append(items, GetPostsWithStateRow{
+			ID:          p.ID,
+			Title:       p.Title,
+			Description: p.Description,
+			Content:     p.Content,
+			Url:         p.Url,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			FeedTitle:   followTitle(follow, m.feeds[p.FeedID]),
+			Read:        state.Read,
+			Starred:     state.Starred,
+		})
+	}
+	slices.SortStableFunc(items, func(a, b GetPostsWithStateRow) int { return byPublishedDesc(a.PublishedAt, b.PublishedAt) })
+	if len(items) > int(arg.MaxPosts) {
+		items = items[:max(arg.MaxPosts, 0)]
+	}
+	return items, nil
+}
+
+// Looks up the state row for an upsert, checking the foreign keys a new row would need
+func (m *MemoryStore) postState(userID, postID uuid.UUID) (PostState, error) {
+	key := postStateKey{UserID: userID, PostID: postID}
+	if state, ok := m.postStates[key]; ok {
+		return state, nil
+	}
+	if _, ok := m.users[userID]; !ok {
+		return PostState{}, fmt.Errorf("%w: post_states.user_id", errMissingReference)
+	}
+	if _, ok := m.posts[postID]; !ok {
+		return PostState{}, fmt.Errorf("%w: post_states.post_id", errMissingReference)
+	}
+	return PostState{UserID: userID, PostID: postID}, nil
+}
+
+func (m *MemoryStore) SetPostRead(ctx context.Context, arg SetPostReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, err := m.postState(arg.UserID, arg.PostID)
+	if err != nil {
+		return err
+	}
+	state.Read = arg.Read
+	state.UpdatedAt = arg.UpdatedAt
+	m.postStates[postStateKey{UserID: arg.UserID, PostID: arg.PostID}] = state
+	return nil
+}
+
+func (m *MemoryStore) SetPostStarred(ctx context.Context, arg SetPostStarredParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, err := m.postState(arg.UserID, arg.PostID)
+	if err != nil {
+		return err
+	}
+	state.Starred = arg.Starred
+	state.UpdatedAt = arg.UpdatedAt
+	m.postStates[postStateKey{UserID: arg.UserID, PostID: arg.PostID}] = state
+	return nil
+}
+
+// Archives
+
+func (m *MemoryStore) ListUsers(ctx context.Context) ([]User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return sortedValues(m.users, func(u User) time.Time { return u.CreatedAt }, func(u User) uuid.UUID { return u.ID }), nil
+}
+
+func (m *MemoryStore) ListFeeds(ctx context.Context) ([]Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedFeeds(), nil
+}
+
+func (m *MemoryStore) ListFolders(ctx context.Context) ([]Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return sortedValues(m.folders, func(f Folder) time.Time { return f.CreatedAt }, func(f Folder) uuid.UUID { return f.ID }), nil
+}
+
+func (m *MemoryStore) ListFeedFollows(ctx context.Context) ([]FeedFollow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedFollows(), nil
+}
+
+func (m *MemoryStore) ListFeedURLHistory(ctx context.Context) ([]FeedUrlHistory, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := slices.Collect(maps.Values(m.urlHistory))
+	slices.SortFunc(items, func(a, b FeedUrlHistory) int {
+		if c := a.MovedAt.Compare(b.MovedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Url, b.Url)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) ListPosts(ctx context.Context) ([]Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedPosts(), nil
+}
+
+// Names attached to posts, ordered by post and then in the order they were added
+func listPostNames[T any](names map[uuid.UUID][]string, row func(uuid.UUID, string) T) []T {
+	ids := slices.Collect(maps.Keys(names))
+	slices.SortFunc(ids, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+
+	var items []T
+	for _, id := range ids {
+		for _, name := range names[id] {
+			items = append(items, row(id, name))
+		}
+	}
+	return items
+}
+
+func (m *MemoryStore) ListPostAuthors(ctx context.Context) ([]PostAuthor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return listPostNames(m.authors, func(id uuid.UUID, name string) PostAuthor { return PostAuthor{PostID: id, Name: name} }), nil
+}
+
+func (m *MemoryStore) ListPostCategories(ctx context.Context) ([]PostCategory, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return listPostNames(m.categories, func(id uuid.UUID, name string) PostCategory { return PostCategory{PostID: id, Name: name} }), nil
+}
+
+func (m *MemoryStore) ListPostStates(ctx context.Context) ([]PostState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := slices.Collect(maps.Values(m.postStates))
+	slices.SortFunc(items, func(a, b PostState) int {
+		if c := strings.Compare(a.UserID.String(), b.UserID.String()); c != 0 {
+			return c
+		}
+		return strings.Compare(a.PostID.String(), b.PostID.String())
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) ListArchivedPosts(ctx context.Context) ([]ArchivedPost, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return sortedValues(m.archived, func(p ArchivedPost) time.Time { return p.ArchivedAt }, func(p ArchivedPost) uuid.UUID { return p.ID }), nil
+}
+
+func (m *MemoryStore) ListPrunedPosts(ctx context.Context) ([]PrunedPost, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := slices.Collect(maps.Values(m.pruned))
+	slices.SortFunc(items, func(a, b PrunedPost) int {
+		if c := a.PrunedAt.Compare(b.PrunedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Url, b.Url)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) ListAPITokens(ctx context.Context) ([]ApiToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return sortedValues(m.apiTokens, func(t ApiToken) time.Time { return t.CreatedAt }, func(t ApiToken) uuid.UUID { return t.ID }), nil
+}
+
+func (m *MemoryStore) RestoreFeed(ctx context.Context, arg RestoreFeedParams) error {
+	feed, err := m.CreateFeed(ctx, CreateFeedParams{
+		ID:            arg.ID,
+		CreatedAt:     arg.CreatedAt,
+		UpdatedAt:     arg.UpdatedAt,
+		Name:          arg.Name,
+		Url:           arg.Url,
+		UserID:        arg.UserID,
+		LastFetchedAt: arg.LastFetchedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed.FetchFullText = arg.FetchFullText
+	feed.Active = arg.Active
+	feed.DeactivatedAt = arg.DeactivatedAt
+	feed.RetentionDays = arg.RetentionDays
+	feed.RetentionPosts = arg.RetentionPosts
+	m.feeds[feed.ID] = feed
+	return nil
+}
+
+func (m *MemoryStore) RestoreFeedFollow(ctx context.Context, arg RestoreFeedFollowParams) error {
+	_, err := m.CreateFeedFollow(ctx, CreateFeedFollowParams{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.FolderID.Valid {
+		if _, ok := m.folders[arg.FolderID.UUID]; !ok {
+			delete(m.follows, arg.ID)
+			return fmt.Errorf("%w: feed_follows.folder_id", errMissingReference)
+		}
+	}
+	follow := m.follows[arg.ID]
+	follow.FolderID = arg.FolderID
+	follow.Title = arg.Title
+	follow.NotifiedAt = arg.NotifiedAt
+	m.follows[arg.ID] = follow
+	return nil
+}
+
+func (m *MemoryStore) RestorePostState(ctx context.Context, arg RestorePostStateParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := postStateKey{UserID: arg.UserID, PostID: arg.PostID}
+	if _, ok := m.postStates[key]; ok {
+		return fmt.Errorf("%w: post_states", errDuplicate)
+	}
+	state, err := m.postState(arg.UserID, arg.PostID)
+	if err != nil {
+		return err
+	}
+	state.Read = arg.Read
+	state.Starred = arg.Starred
+	state.UpdatedAt = arg.UpdatedAt
+	m.postStates[key] = state
+	return nil
+}
+
+func (m *MemoryStore) RestoreArchivedPost(ctx context.Context, arg RestoreArchivedPostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feeds[arg.FeedID]; !ok {
+		return fmt.Errorf("%w: archived_posts.feed_id", errMissingReference)
+	}
+	if _, ok := m.archived[arg.ID]; ok {
+		return fmt.Errorf("%w: archived_posts", errDuplicate)
+	}
+	m.archived[arg.ID] = ArchivedPost(arg)
+	return nil
+}
+
+func (m *MemoryStore) RestorePrunedPost(ctx context.Context, arg RestorePrunedPostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feeds[arg.FeedID]; !ok {
+		return fmt.Errorf("%w: pruned_posts.feed_id", errMissingReference)
+	}
+	if _, ok := m.pruned[arg.Url]; ok {
+		return fmt.Errorf("%w: pruned_posts", errDuplicate)
+	}
+	m.pruned[arg.Url] = PrunedPost(arg)
+	return nil
+}
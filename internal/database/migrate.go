@@ -0,0 +1,345 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/sql/schema"
+)
+
+// A single numbered file from sql/schema
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// A migration along with whether and when it was applied to the database
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+const versionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL
+)`
+
+// Reads the embedded migrations for the kind of database db is, sorted by version
+func Migrations(db *sql.DB) ([]Migration, error) {
+	var migrationFS fs.FS = schema.FS
+	if isSQLiteDB(db) {
+		sub, err := fs.Sub(schema.SQLite, "sqlite")
+		if err != nil {
+			return nil, err
+		}
+		migrationFS = sub
+	}
+
+	files, err := fs.Glob(migrationFS, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(file, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s doesn't start with a version number", file)
+		}
+
+		data, err := fs.ReadFile(migrationFS, file)
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := splitMigration(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", file, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    strings.TrimSuffix(path.Base(file), ".sql"),
+			up:      up,
+			down:    down,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s share a version", migrations[i-1].Name, migrations[i].Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Splits a goose annotated file into its up and down sections
+func splitMigration(text string) (string, string, error) {
+	var up, down strings.Builder
+	var section *strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			section = &up
+			continue
+		case "-- +goose Down":
+			section = &down
+			continue
+		case "-- +goose StatementBegin", "-- +goose StatementEnd":
+			// Whole sections are sent at once, so statements need no special grouping
+			continue
+		}
+		if section != nil {
+			section.WriteString(line)
+			section.WriteString("\n")
+		}
+	}
+
+	if strings.TrimSpace(up.String()) == "" {
+		return "", "", errors.New("no -- +goose Up section")
+	}
+	return up.String(), down.String(), nil
+}
+
+// Applies every migration the database doesn't have yet.
+// It refuses to touch a database whose schema is newer than this build of gator knows about.
+func Migrate(db *sql.DB) error {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Applying migration %s...\n", migration.Name)
+		err := runMigration(db, migration, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rolls back the most recently applied migration and returns it
+func MigrateDown(db *sql.DB) (Migration, error) {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[migrations[i].Version]; !ok {
+			continue
+		}
+		if strings.TrimSpace(migrations[i].down) == "" {
+			return Migration{}, fmt.Errorf("migration %s can't be rolled back, it has no -- +goose Down section", migrations[i].Name)
+		}
+		return migrations[i], runMigration(db, migrations[i], false)
+	}
+
+	return Migration{}, errors.New("no migrations to roll back")
+}
+
+// Rolls back the applied migration called name while fn runs and applies it again afterwards,
+// so the difference it makes can be measured. Later migrations are left as they are.
+func WithoutMigration(db *sql.DB, name string, fn func() error) error {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if migration.Name != name {
+			continue
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			return fmt.Errorf("migration %s hasn't been applied", name)
+		}
+		err = runMigration(db, migration, false)
+		if err != nil {
+			return err
+		}
+		fnErr := fn()
+		err = runMigration(db, migration, true)
+		if err != nil {
+			return err
+		}
+		return fnErr
+	}
+
+	return fmt.Errorf("no migration called %s", name)
+}
+
+// Lists every known migration and whether the database has it
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return status, nil
+}
+
+// Reads the embedded migrations and the versions recorded in the database,
+// making sure the database isn't ahead of this build
+func loadMigrationState(db *sql.DB) ([]Migration, map[int]time.Time, error) {
+	migrations, err := Migrations(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// SQLite support came with versioned migrations, so only PostgreSQL can have an untracked schema
+	if isSQLiteDB(db) {
+		_, err = db.Exec(versionTable)
+	} else {
+		err = adoptLegacySchema(db)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema versions: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return nil, nil, fmt.Errorf("ERROR: The database schema is at version %d but this gator only knows up to version %d, upgrade gator or roll the database back with a newer one", version, latest)
+		}
+	}
+
+	return migrations, applied, nil
+}
+
+// Runs one side of a migration and records the result in the same transaction
+func runMigration(db *sql.DB, migration Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		_, err = tx.Exec(migration.up)
+		if err == nil {
+			_, err = tx.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)", migration.Version, time.Now())
+		}
+	} else {
+		_, err = tx.Exec(migration.down)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run migration %s: %w", migration.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// What each migration leaves behind, used to tell how far along a database is
+// that was set up before gator kept track of schema versions
+var legacyMarkers = []struct {
+	version int
+	table   string
+	column  string
+}{
+	{1, "users", ""},
+	{2, "feeds", ""},
+	{3, "feed_follows", ""},
+	{4, "feeds", "last_fetched_at"},
+	{5, "posts", ""},
+	{6, "post_authors", ""},
+	{7, "feeds", "fetch_full_text"},
+	{8, "post_states", ""},
+	{9, "folders", ""},
+	{10, "feed_follows", "title"},
+	{11, "users", "is_admin"},
+	{12, "feed_url_history", ""},
+}
+
+// Creates the version table, and when the database already has gator's tables but no versions,
+// records the migrations whose changes are already there so they aren't applied twice
+func adoptLegacySchema(db *sql.DB) error {
+	var exists bool
+	err := db.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check for schema versions: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = db.Exec(versionTable)
+	if err != nil {
+		return fmt.Errorf("failed to create schema version table: %w", err)
+	}
+
+	for _, marker := range legacyMarkers {
+		var present bool
+		err := db.QueryRow(`SELECT EXISTS (
+		    SELECT 1 FROM information_schema.columns
+		    WHERE table_schema = current_schema()
+		    AND table_name = $1
+		    AND ($2 = '' OR column_name = $2)
+		)`, marker.table, marker.column).Scan(&present)
+		if err != nil {
+			return fmt.Errorf("failed to inspect existing schema: %w", err)
+		}
+		if !present {
+			break
+		}
+
+		_, err = db.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)", marker.version, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -5,27 +5,71 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	TokenHash string
+}
+
+type ArchivedPost struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt string
+	FeedID      uuid.UUID
+	Content     string
+	ArchivedAt  time.Time
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt time.Time
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Name           string
+	Url            string
+	UserID         uuid.UUID
+	LastFetchedAt  time.Time
+	FetchFullText  bool
+	Active         bool
+	DeactivatedAt  sql.NullTime
+	RetentionDays  sql.NullInt32
+	RetentionPosts sql.NullInt32
 }
 
 type FeedFollow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      string
+	NotifiedAt time.Time
+}
+
+type FeedUrlHistory struct {
+	Url     string
+	FeedID  uuid.UUID
+	MovedAt time.Time
+}
+
+type Folder struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	Name      string
 }
 
 type Post struct {
@@ -37,6 +81,31 @@ type Post struct {
 	Description string
 	PublishedAt string
 	FeedID      uuid.UUID
+	Content     string
+}
+
+type PostAuthor struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Read      bool
+	Starred   bool
+	UpdatedAt time.Time
+}
+
+type PrunedPost struct {
+	Url      string
+	FeedID   uuid.UUID
+	PrunedAt time.Time
 }
 
 type User struct {
@@ -44,4 +113,13 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	IsAdmin   bool
+}
+
+type WebSession struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	ApiTokenID uuid.UUID
+	TokenHash  string
 }
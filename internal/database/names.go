@@ -0,0 +1,24 @@
+package database
+
+import "strings"
+
+// Separates the names GetPostsForUser aggregates into authors and categories. It's the ASCII
+// unit separator, which can't be part of a name since feed text is stripped of control characters.
+const NameSeparator = "\x1f"
+
+func splitNames(names string) []string {
+	if names == "" {
+		return []string{}
+	}
+	return strings.Split(names, NameSeparator)
+}
+
+// The post's authors, one name each
+func (r GetPostsForUserRow) AuthorNames() []string {
+	return splitNames(r.Authors)
+}
+
+// The post's categories, one name each
+func (r GetPostsForUserRow) CategoryNames() []string {
+	return splitNames(r.Categories)
+}
@@ -7,13 +7,14 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content)
 VALUES (
     $1,
     $2,
@@ -22,9 +23,10 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content
 `
 
 type CreatePostParams struct {
@@ -36,6 +38,7 @@ type CreatePostParams struct {
 	Description string
 	PublishedAt string
 	FeedID      uuid.UUID
+	Content     string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -48,6 +51,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Content,
 	)
 	var i Post
 	err := row.Scan(
@@ -59,50 +63,261 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
 	)
 	return i, err
 }
 
+const getPostsByIDPrefix = `-- name: GetPostsByIDPrefix :many
+SELECT posts.id, posts.title, posts.url, posts.published_at FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND posts.id::TEXT LIKE $2::TEXT || '%'
+ORDER BY posts.id
+LIMIT 10
+`
+
+type GetPostsByIDPrefixParams struct {
+	UserID uuid.UUID
+	Prefix string
+}
+
+type GetPostsByIDPrefixRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt string
+}
+
+func (q *Queries) GetPostsByIDPrefix(ctx context.Context, arg GetPostsByIDPrefixParams) ([]GetPostsByIDPrefixRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByIDPrefix, arg.UserID, arg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsByIDPrefixRow
+	for rows.Next() {
+		var i GetPostsByIDPrefixRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePostContent = `-- name: UpdatePostContent :exec
+UPDATE posts SET
+    content = $2,
+    updated_at = $3
+WHERE id = $1
+`
+
+type UpdatePostContentParams struct {
+	ID        uuid.UUID
+	Content   string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContent, arg.ID, arg.Content, arg.UpdatedAt)
+	return err
+}
+
+const createPostAuthor = `-- name: CreatePostAuthor :exec
+INSERT INTO post_authors (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostAuthorParams struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) CreatePostAuthor(ctx context.Context, arg CreatePostAuthorParams) error {
+	_, err := q.db.ExecContext(ctx, createPostAuthor, arg.PostID, arg.Name)
+	return err
+}
+
+const createPostCategory = `-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostCategoryParams struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, createPostCategory, arg.PostID, arg.Name)
+	return err
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.title, posts.description, posts.url, posts.published_at, feeds.name AS feed_title
+SELECT posts.id, posts.title, posts.description, posts.content, posts.url, posts.published_at, COALESCE(NULLIF(feed_follows.title, ''), feeds.name) AS feed_title,
+    COALESCE((SELECT string_agg(post_authors.name, chr(31)) FROM post_authors WHERE post_authors.post_id = posts.id), '')::TEXT AS authors,
+    COALESCE((SELECT string_agg(post_categories.name, chr(31)) FROM post_categories WHERE post_categories.post_id = posts.id), '')::TEXT AS categories,
+    COALESCE(post_states.read, FALSE)::BOOLEAN AS read,
+    COALESCE(post_states.starred, FALSE)::BOOLEAN AS starred
 FROM posts
-INNER JOIN feed_follows
-ON posts.feed_id = feed_follows.feed_id
+CROSS JOIN feed_follows
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY published_at DESC
+LEFT JOIN folders
+ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id
+AND post_states.user_id = feed_follows.user_id
+WHERE posts.feed_id = feed_follows.feed_id
+AND feed_follows.user_id = $1
+AND ($2::TEXT = '' OR lower(folders.name) = lower($2::TEXT))
+AND ($3::TEXT = '' OR EXISTS (
+    SELECT 1 FROM post_authors
+    WHERE post_authors.post_id = posts.id
+    AND post_authors.name ILIKE '%' || $3::TEXT || '%'
+))
+AND ($4::TEXT = '' OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    AND lower(post_categories.name) = lower($4::TEXT)
+))
+AND (NOT $5::BOOLEAN OR COALESCE(post_states.starred, FALSE))
+AND ($6::TEXT IS NULL
+    OR (posts.published_at, posts.id) < ($6::TEXT, $7::UUID))
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT $8
 `
 
+type GetPostsForUserParams struct {
+	UserID            uuid.UUID
+	Folder            string
+	Author            string
+	Category          string
+	StarredOnly       bool
+	BeforePublishedAt sql.NullString
+	BeforeID          uuid.NullUUID
+	MaxPosts          int32
+}
+
 type GetPostsForUserRow struct {
+	ID          uuid.UUID
 	Title       string
 	Description string
+	Content     string
 	Url         string
 	PublishedAt string
 	FeedTitle   string
+	Authors     string
+	Categories  string
+	Read        bool
+	Starred     bool
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, userID)
+// CROSS JOIN is an inner join to PostgreSQL, but it makes SQLite walk posts newest first
+// through the index and stop at the limit, rather than sorting every post the user follows
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Folder,
+		arg.Author,
+		arg.Category,
+		arg.StarredOnly,
+		arg.BeforePublishedAt,
+		arg.BeforeID,
+		arg.MaxPosts,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-
 	var items []GetPostsForUserRow
 	for rows.Next() {
 		var i GetPostsForUserRow
-
-		if err := rows.Scan(&i.Title, &i.Description, &i.Url, &i.PublishedAt, &i.FeedTitle); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Content,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedTitle,
+			&i.Authors,
+			&i.Categories,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-
 	return items, nil
 }
 
+const deleteAllPosts = `-- name: DeleteAllPosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeleteAllPosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllPosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostIDNeighbours = `-- name: GetPostIDNeighbours :one
+SELECT
+    (SELECT posts.id FROM posts
+        INNER JOIN feed_follows
+        ON feed_follows.feed_id = posts.feed_id
+        WHERE feed_follows.user_id = $1
+        AND posts.id < $2
+        ORDER BY posts.id DESC
+        LIMIT 1) AS previous_id,
+    (SELECT posts.id FROM posts
+        INNER JOIN feed_follows
+        ON feed_follows.feed_id = posts.feed_id
+        WHERE feed_follows.user_id = $1
+        AND posts.id > $2
+        ORDER BY posts.id
+        LIMIT 1) AS next_id
+`
+
+type GetPostIDNeighboursParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+type GetPostIDNeighboursRow struct {
+	PreviousID uuid.NullUUID
+	NextID     uuid.NullUUID
+}
+
+// The ids either side of @id among the posts the user follows, which decide how much of it is needed to tell it apart
+func (q *Queries) GetPostIDNeighbours(ctx context.Context, arg GetPostIDNeighboursParams) (GetPostIDNeighboursRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostIDNeighbours, arg.UserID, arg.ID)
+	var i GetPostIDNeighboursRow
+	err := row.Scan(&i.PreviousID, &i.NextID)
+	return i, err
+}
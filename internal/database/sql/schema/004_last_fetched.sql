@@ -1,7 +0,0 @@
--- +goose Up
-ALTER TABLE feeds
-ADD COLUMN last_fetched_at TIMESTAMP;
-
--- +goose Down
-ALTER TABLE feeds
-DROP COLUMN last_fetched_at;
\ No newline at end of file
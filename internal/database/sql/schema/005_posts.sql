@@ -1,15 +0,0 @@
--- +goose Up
-CREATE TABLE posts (
-    id UUID PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
-    title TEXT NOT NULL,
-    url TEXT NOT NULL UNIQUE,
-    description TEXT NOT NULL,
-    published_at TEXT NOT NULL,
-    feed_id UUID NOT NULL,
-    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
-);
-
--- +goose Down
-DROP TABLE posts;
\ No newline at end of file
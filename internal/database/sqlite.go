@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// db_url prefix that selects the SQLite backend, e.g. sqlite://~/.gator.db
+const sqliteScheme = "sqlite:"
+
+// Options set on every SQLite connection. Foreign keys are off by default in SQLite,
+// and the cascading deletes rely on them.
+var sqlitePragmas = []string{"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"}
+
+var (
+	// PostgreSQL casts like ::TEXT and ::UUID, SQLite's types are loose enough to go without
+	pgCast = regexp.MustCompile(`::[A-Za-z]+`)
+	ilike  = regexp.MustCompile(`(?i)\bILIKE\b`)
+	// PostgreSQL's chr() is char() in SQLite
+	pgChr = regexp.MustCompile(`(?i)\bchr\(`)
+)
+
+// Reports whether db_url points at a SQLite file rather than a PostgreSQL server
+func IsSQLite(dsn string) bool {
+	return strings.HasPrefix(dsn, sqliteScheme)
+}
+
+// Turns sqlite://path, sqlite:///abs/path or sqlite:path into the file name the driver expects, with a leading ~ expanded
+func sqlitePath(dsn string) (string, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(dsn, sqliteScheme), "//")
+	if path == "" {
+		return "", fmt.Errorf("ERROR: No file name in db_url %s", dsn)
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return path, nil
+}
+
+// Opens the SQLite file, creating it and its directory if needed
+func openSQLite(dsn string) (*sql.DB, error) {
+	path, err := sqlitePath(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	file, _, _ := strings.Cut(path, "?")
+	if dir := filepath.Dir(file); dir != "." {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: Cannot create directory for %s: %w", file, err)
+		}
+	}
+
+	for _, pragma := range sqlitePragmas {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + "_pragma=" + pragma
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Cannot open %s: %w", file, err)
+	}
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ERROR: Cannot open %s: %w", file, err)
+	}
+	return db, nil
+}
+
+// Reports whether err comes from inserting a row that already exists, on either backend
+func IsUniqueViolation(err error) bool {
+	if errors.Is(err, errDuplicate) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+func isSQLiteDB(db *sql.DB) bool {
+	_, ok := db.Driver().(*sqlite.Driver)
+	return ok
+}
+
+// Returns the queries for an open database of either kind
+func NewStore(db *sql.DB) *Queries {
+	if isSQLiteDB(db) {
+		return New(sqliteDB{db})
+	}
+	return New(db)
+}
+
+// Runs the PostgreSQL flavoured queries against SQLite. The queries stick to SQL both
+// understand, apart from casts, ILIKE and chr(), which are rewritten here.
+type sqliteDB struct {
+	db DBTX
+}
+
+var sqliteQueries sync.Map
+
+func toSQLite(query string) string {
+	if translated, ok := sqliteQueries.Load(query); ok {
+		return translated.(string)
+	}
+	translated := ilike.ReplaceAllString(pgCast.ReplaceAllString(query, ""), "LIKE")
+	translated = pgChr.ReplaceAllString(translated, "char(")
+	sqliteQueries.Store(query, translated)
+	return translated
+}
+
+func (s sqliteDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(ctx, toSQLite(query), args...)
+}
+
+func (s sqliteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return s.db.PrepareContext(ctx, toSQLite(query))
+}
+
+func (s sqliteDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, toSQLite(query), args...)
+}
+
+func (s sqliteDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRowContext(ctx, toSQLite(query), args...)
+}
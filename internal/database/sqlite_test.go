@@ -0,0 +1,23 @@
+package database
+
+import "testing"
+
+func TestToSQLite(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT id::TEXT FROM feeds", "SELECT id FROM feeds"},
+		{"WHERE (@folder::TEXT = '' OR x)", "WHERE (@folder = '' OR x)"},
+		{"COALESCE(read, FALSE)::BOOLEAN AS read", "COALESCE(read, FALSE) AS read"},
+		{"WHERE name ILIKE '%' || $1 || '%'", "WHERE name LIKE '%' || $1 || '%'"},
+		{"WHERE name ilike $1", "WHERE name LIKE $1"},
+		{"string_agg(name, chr(31))", "string_agg(name, char(31))"},
+	}
+
+	for _, tt := range tests {
+		if got := toSQLite(tt.query); got != tt.want {
+			t.Errorf("toSQLite(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Everything gator needs from its database. Queries implements it for PostgreSQL and SQLite alike,
+// see NewStore.
+type Store interface {
+	// Runs fn as one unit of work, see Queries.InTx
+	InTx(ctx context.Context, fn func(Store) error) error
+
+	// Users
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUsers(ctx context.Context) ([]string, error)
+	SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error)
+	DeleteUser(ctx context.Context, name string) (int64, error)
+	ResetUsers(ctx context.Context) error
+
+	// API tokens
+	CreateAPIToken(ctx context.Context, arg CreateAPITokenParams) (ApiToken, error)
+	GetAPIToken(ctx context.Context, tokenHash string) (ApiToken, error)
+	GetUserByAPIToken(ctx context.Context, tokenHash string) (User, error)
+	GetAPITokensForUser(ctx context.Context, userID uuid.UUID) ([]ApiToken, error)
+	DeleteAPIToken(ctx context.Context, arg DeleteAPITokenParams) (int64, error)
+
+	// Web sessions
+	CreateWebSession(ctx context.Context, arg CreateWebSessionParams) (WebSession, error)
+	GetUserByWebSession(ctx context.Context, arg GetUserByWebSessionParams) (User, error)
+	DeleteWebSession(ctx context.Context, tokenHash string) (int64, error)
+	DeleteExpiredWebSessions(ctx context.Context, expiresAt time.Time) (int64, error)
+
+	// Feeds
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteAllFeeds(ctx context.Context) (int64, error)
+	GetCreator(ctx context.Context, id uuid.UUID) (string, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeeds(ctx context.Context) ([]GetFeedsRow, error)
+	GetFeedsByNameOrIDPrefix(ctx context.Context, arg GetFeedsByNameOrIDPrefixParams) ([]Feed, error)
+	MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) (int64, error)
+	MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) (int64, error)
+	ReassignFeeds(ctx context.Context, arg ReassignFeedsParams) (int64, error)
+	SetFeedActive(ctx context.Context, arg SetFeedActiveParams) error
+	SetFeedFullText(ctx context.Context, arg SetFeedFullTextParams) error
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error
+	SetFeedURL(ctx context.Context, arg SetFeedURLParams) error
+	UpdateFeed(ctx context.Context, arg UpdateFeedParams) (Feed, error)
+	URLLookup(ctx context.Context, url string) (URLLookupRow, error)
+
+	// Follows
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	MarkFollowsNotified(ctx context.Context, arg MarkFollowsNotifiedParams) error
+	SetFollowTitle(ctx context.Context, arg SetFollowTitleParams) (int64, error)
+	DeleteFollow(ctx context.Context, arg DeleteFollowParams) (int64, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+
+	// Folders
+	CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error)
+	DeleteFolder(ctx context.Context, id uuid.UUID) error
+	GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error)
+	GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]Folder, error)
+	SetFollowFolder(ctx context.Context, arg SetFollowFolderParams) (int64, error)
+
+	// Feed url history
+	AddFeedURLHistory(ctx context.Context, arg AddFeedURLHistoryParams) error
+	DeleteFeedURLHistory(ctx context.Context, url string) error
+	GetFeedMovesForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedMovesForUserRow, error)
+	MoveFeedURLHistory(ctx context.Context, arg MoveFeedURLHistoryParams) error
+
+	// Fetch scheduling
+	GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow, error)
+	MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error
+
+	// Posts
+	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	DeleteAllPosts(ctx context.Context) (int64, error)
+	GetPostsByIDPrefix(ctx context.Context, arg GetPostsByIDPrefixParams) ([]GetPostsByIDPrefixRow, error)
+	GetPostIDNeighbours(ctx context.Context, arg GetPostIDNeighboursParams) (GetPostIDNeighboursRow, error)
+	UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error
+	CreatePostAuthor(ctx context.Context, arg CreatePostAuthorParams) error
+	CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error)
+
+	// Retention
+	GetPostsToPrune(ctx context.Context, arg GetPostsToPruneParams) ([]uuid.UUID, error)
+	AddPrunedPost(ctx context.Context, arg AddPrunedPostParams) error
+	ArchivePost(ctx context.Context, arg ArchivePostParams) error
+	DeletePost(ctx context.Context, id uuid.UUID) error
+	IsPostPruned(ctx context.Context, url string) (bool, error)
+	MoveArchivedPosts(ctx context.Context, arg MoveArchivedPostsParams) (int64, error)
+	MovePrunedPosts(ctx context.Context, arg MovePrunedPostsParams) error
+
+	// Read and starred state
+	GetFeedsWithUnreadCount(ctx context.Context, userID uuid.UUID) ([]GetFeedsWithUnreadCountRow, error)
+	GetPostsWithState(ctx context.Context, arg GetPostsWithStateParams) ([]GetPostsWithStateRow, error)
+	SetPostRead(ctx context.Context, arg SetPostReadParams) error
+	SetPostStarred(ctx context.Context, arg SetPostStarredParams) error
+
+	// Every row of each table, and loading them back, for archives
+	ListUsers(ctx context.Context) ([]User, error)
+	ListFeeds(ctx context.Context) ([]Feed, error)
+	ListFolders(ctx context.Context) ([]Folder, error)
+	ListFeedFollows(ctx context.Context) ([]FeedFollow, error)
+	ListFeedURLHistory(ctx context.Context) ([]FeedUrlHistory, error)
+	ListPosts(ctx context.Context) ([]Post, error)
+	ListPostAuthors(ctx context.Context) ([]PostAuthor, error)
+	ListPostCategories(ctx context.Context) ([]PostCategory, error)
+	ListPostStates(ctx context.Context) ([]PostState, error)
+	ListArchivedPosts(ctx context.Context) ([]ArchivedPost, error)
+	ListPrunedPosts(ctx context.Context) ([]PrunedPost, error)
+	ListAPITokens(ctx context.Context) ([]ApiToken, error)
+	RestoreFeed(ctx context.Context, arg RestoreFeedParams) error
+	RestoreFeedFollow(ctx context.Context, arg RestoreFeedFollowParams) error
+	RestorePostState(ctx context.Context, arg RestorePostStateParams) error
+	RestoreArchivedPost(ctx context.Context, arg RestoreArchivedPostParams) error
+	RestorePrunedPost(ctx context.Context, arg RestorePrunedPostParams) error
+}
+
+var _ Store = (*Queries)(nil)
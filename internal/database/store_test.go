@@ -0,0 +1,704 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/database/dbtest"
+	"github.com/google/uuid"
+)
+
+func postTitles[T any](rows []T, title func(T) string) []string {
+	titles := []string{}
+	for _, row := range rows {
+		titles = append(titles, title(row))
+	}
+	return titles
+}
+
+func checkTitles(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("%s = %q, want %q", what, got, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s database.Store)
+	}{
+		{"duplicate names and urls are unique violations", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			_, err := s.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: dbtest.Time, UpdatedAt: dbtest.Time, Name: "alice"})
+			if !database.IsUniqueViolation(err) {
+				t.Errorf("second alice: got %v, want a unique violation", err)
+			}
+
+			feed := dbtest.CreateFeed(t, s, user, "Blog", "https://example.com/feed")
+			_, err = s.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: dbtest.Time, UpdatedAt: dbtest.Time, Name: "Copy", Url: feed.Url, UserID: user.ID})
+			if !database.IsUniqueViolation(err) {
+				t.Errorf("second feed with the same url: got %v, want a unique violation", err)
+			}
+
+			dbtest.Follow(t, s, user, feed)
+			_, err = s.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: dbtest.Time, UpdatedAt: dbtest.Time, UserID: user.ID, FeedID: feed.ID})
+			if !database.IsUniqueViolation(err) {
+				t.Errorf("second follow: got %v, want a unique violation", err)
+			}
+		}},
+
+		{"unfollowing counts the follows deleted", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			alice := dbtest.CreateUser(t, s, "alice", false)
+			bob := dbtest.CreateUser(t, s, "bob", false)
+			feed := dbtest.CreateFeed(t, s, alice, "Blog", "https://example.com/feed")
+			dbtest.Follow(t, s, alice, feed)
+			dbtest.Follow(t, s, bob, feed)
+
+			for _, want := range []int64{1, 0} {
+				rows, err := s.DeleteFollow(ctx, database.DeleteFollowParams{Name: "alice", Url: feed.Url})
+				if err != nil || rows != want {
+					t.Errorf("DeleteFollow(alice) = %d, %v, want %d rows", rows, err, want)
+				}
+			}
+			if follows, err := s.GetFeedFollowsForUser(ctx, bob.ID); err != nil || len(follows) != 1 {
+				t.Errorf("bob's follows = %+v, %v, want Blog", follows, err)
+			}
+		}},
+
+		{"users are listed and made admin", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			dbtest.CreateUser(t, s, "carol", false)
+			dbtest.CreateUser(t, s, "alice", false)
+			users, err := s.GetUsers(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// GetUsers has no order of its own
+			slices.Sort(users)
+			checkTitles(t, "GetUsers", users, []string{"alice", "carol"})
+
+			rows, err := s.SetUserAdmin(ctx, database.SetUserAdminParams{Name: "carol", IsAdmin: true, UpdatedAt: dbtest.Time})
+			if err != nil || rows != 1 {
+				t.Fatalf("SetUserAdmin(carol) = %d, %v, want 1 row", rows, err)
+			}
+			if rows, err := s.SetUserAdmin(ctx, database.SetUserAdminParams{Name: "nobody", IsAdmin: true, UpdatedAt: dbtest.Time}); err != nil || rows != 0 {
+				t.Errorf("SetUserAdmin(nobody) = %d, %v, want 0 rows", rows, err)
+			}
+			carol, err := s.GetUser(ctx, "carol")
+			if err != nil || !carol.IsAdmin {
+				t.Errorf("GetUser(carol) = %+v, %v, want an admin", carol, err)
+			}
+			if _, err := s.GetUser(ctx, "nobody"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetUser(nobody) error = %v, want sql.ErrNoRows", err)
+			}
+		}},
+
+		{"feeds are found by name and id prefix", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			feed := dbtest.CreateFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+			dbtest.CreateFeed(t, s, user, "Rust Blog", "https://blog.rust-lang.org/feed.xml")
+
+			for _, arg := range []database.GetFeedsByNameOrIDPrefixParams{
+				{Name: "go blog"},
+				{Name: "GO BLOG"},
+				{Name: feed.ID.String()[:8], IDPrefix: feed.ID.String()[:8]},
+			} {
+				feeds, err := s.GetFeedsByNameOrIDPrefix(ctx, arg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(feeds) != 1 || feeds[0].ID != feed.ID {
+					t.Errorf("GetFeedsByNameOrIDPrefix(%+v) = %+v, want only Go Blog", arg, feeds)
+				}
+			}
+			if feeds, err := s.GetFeedsByNameOrIDPrefix(ctx, database.GetFeedsByNameOrIDPrefixParams{Name: "Blog"}); err != nil || len(feeds) != 0 {
+				t.Errorf("GetFeedsByNameOrIDPrefix(Blog) = %+v, %v, want nothing", feeds, err)
+			}
+		}},
+
+		{"feeds found by name come before feeds found by id", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			for i := range 11 {
+				_, err := s.CreateFeed(ctx, database.CreateFeedParams{
+					ID:        uuid.MustParse(fmt.Sprintf("aaaaaaaa-0000-4000-8000-%012d", i)),
+					CreatedAt: dbtest.Time,
+					UpdatedAt: dbtest.Time,
+					Name:      fmt.Sprintf("Feed %d", i),
+					Url:       fmt.Sprintf("https://example.com/%d", i),
+					UserID:    user.ID,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			named := dbtest.CreateFeed(t, s, user, "aaaaaaaa", "https://example.com/named")
+
+			feeds, err := s.GetFeedsByNameOrIDPrefix(ctx, database.GetFeedsByNameOrIDPrefixParams{Name: "AAAAAAAA", IDPrefix: "aaaaaaaa"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(feeds) != 10 || feeds[0].ID != named.ID {
+				t.Errorf("GetFeedsByNameOrIDPrefix = %d feeds starting with %s, want 10 starting with the named one", len(feeds), feeds[0].Name)
+			}
+		}},
+
+		{"a moved feed is found by its old url", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			feed := dbtest.CreateFeed(t, s, user, "Blog", "http://example.com/rss")
+			newURL := "https://example.com/feed.xml"
+			if err := s.SetFeedURL(ctx, database.SetFeedURLParams{ID: feed.ID, Url: newURL, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.AddFeedURLHistory(ctx, database.AddFeedURLHistoryParams{Url: feed.Url, FeedID: feed.ID, MovedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, url := range []string{feed.Url, newURL} {
+				found, err := s.URLLookup(ctx, url)
+				if err != nil || found.ID != feed.ID {
+					t.Errorf("URLLookup(%q) = %+v, %v, want the Blog feed", url, found, err)
+				}
+			}
+			if _, err := s.URLLookup(ctx, "https://elsewhere.example/feed"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("URLLookup of an unknown url error = %v, want sql.ErrNoRows", err)
+			}
+		}},
+
+		{"posts are filtered by author, category and folder", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			feed := dbtest.CreateFeed(t, s, user, "Blog", "https://example.com/feed")
+			other := dbtest.CreateFeed(t, s, user, "News", "https://news.example/feed")
+			dbtest.Follow(t, s, user, feed)
+			dbtest.Follow(t, s, user, other)
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Generics", Day: 1, Authors: []string{"Doe, Jane", "Rob Pike"}, Categories: []string{"Go", "Language"}})
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Borrowing", Day: 2, Authors: []string{"Ferris"}, Categories: []string{"Rust"}})
+			dbtest.CreatePost(t, s, other, dbtest.Post{Title: "Headlines", Day: 3})
+
+			folder, err := s.CreateFolder(ctx, database.CreateFolderParams{ID: uuid.New(), CreatedAt: dbtest.Time, UpdatedAt: dbtest.Time, UserID: user.ID, Name: "Tech"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.SetFollowFolder(ctx, database.SetFollowFolderParams{UserID: user.ID, FeedID: feed.ID, FolderID: uuid.NullUUID{UUID: folder.ID, Valid: true}, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+
+			filters := []struct {
+				params database.GetPostsForUserParams
+				want   []string
+			}{
+				{database.GetPostsForUserParams{}, []string{"Headlines", "Borrowing", "Generics"}},
+				{database.GetPostsForUserParams{Author: "jane"}, []string{"Generics"}},
+				{database.GetPostsForUserParams{Author: "doe, j"}, []string{"Generics"}},
+				{database.GetPostsForUserParams{Author: "PIKE"}, []string{"Generics"}},
+				{database.GetPostsForUserParams{Category: "rust"}, []string{"Borrowing"}},
+				{database.GetPostsForUserParams{Category: "lang"}, []string{}},
+				{database.GetPostsForUserParams{Folder: "tech"}, []string{"Borrowing", "Generics"}},
+				{database.GetPostsForUserParams{Folder: "tech", Author: "ferris"}, []string{"Borrowing"}},
+			}
+			for _, f := range filters {
+				f.params.UserID = user.ID
+				f.params.MaxPosts = 10
+				rows, err := s.GetPostsForUser(ctx, f.params)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkTitles(t, "GetPostsForUser", postTitles(rows, func(r database.GetPostsForUserRow) string { return r.Title }), f.want)
+			}
+
+			rows, err := s.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: user.ID, Category: "go", MaxPosts: 10})
+			if err != nil || len(rows) != 1 {
+				t.Fatalf("GetPostsForUser(go) = %+v, %v", rows, err)
+			}
+			// Names come back whole even when they have commas in them
+			authors, categories := rows[0].AuthorNames(), rows[0].CategoryNames()
+			slices.Sort(authors)
+			slices.Sort(categories)
+			checkTitles(t, "Generics' authors", authors, []string{"Doe, Jane", "Rob Pike"})
+			checkTitles(t, "Generics' categories", categories, []string{"Go", "Language"})
+			if rows[0].FeedTitle != "Blog" {
+				t.Errorf("Generics is from %q, want Blog", rows[0].FeedTitle)
+			}
+
+			rows, err = s.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: user.ID, Author: "ferris", MaxPosts: 10})
+			if err != nil || len(rows) != 1 {
+				t.Fatalf("GetPostsForUser(ferris) = %+v, %v", rows, err)
+			}
+			if len(rows[0].AuthorNames()) != 1 || len(rows[0].CategoryNames()) != 1 {
+				t.Errorf("Borrowing has authors %q and categories %q", rows[0].AuthorNames(), rows[0].CategoryNames())
+			}
+			rows, err = s.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: user.ID, MaxPosts: 1})
+			if err != nil || len(rows) != 1 {
+				t.Fatalf("GetPostsForUser(newest) = %+v, %v", rows, err)
+			}
+			if names := rows[0].AuthorNames(); len(names) != 0 {
+				t.Errorf("Headlines has authors %q, want none", names)
+			}
+		}},
+
+		{"posts are paged newest first", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			feed := dbtest.CreateFeed(t, s, user, "Blog", "https://example.com/feed")
+			dbtest.Follow(t, s, user, feed)
+			for day, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+				dbtest.CreatePost(t, s, feed, dbtest.Post{Title: title, Day: day + 1})
+			}
+
+			var got []string
+			params := database.GetPostsForUserParams{UserID: user.ID, MaxPosts: 2}
+			for page := 0; page < 5; page++ {
+				rows, err := s.GetPostsForUser(ctx, params)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(rows) == 0 {
+					break
+				}
+				got = append(got, postTitles(rows, func(r database.GetPostsForUserRow) string { return r.Title })...)
+				last := rows[len(rows)-1]
+				params.BeforePublishedAt = sql.NullString{String: last.PublishedAt, Valid: true}
+				params.BeforeID = uuid.NullUUID{UUID: last.ID, Valid: true}
+			}
+			checkTitles(t, "pages", got, []string{"Five", "Four", "Three", "Two", "One"})
+		}},
+
+		{"read and starred state is per user", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			alice := dbtest.CreateUser(t, s, "alice", false)
+			bob := dbtest.CreateUser(t, s, "bob", false)
+			feed := dbtest.CreateFeed(t, s, alice, "Blog", "https://example.com/feed")
+			dbtest.Follow(t, s, alice, feed)
+			dbtest.Follow(t, s, bob, feed)
+			first := dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "First", Day: 1})
+			second := dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Second", Day: 2})
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Third", Day: 3})
+
+			if err := s.SetPostRead(ctx, database.SetPostReadParams{UserID: alice.ID, PostID: first.ID, Read: true, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SetPostStarred(ctx, database.SetPostStarredParams{UserID: alice.ID, PostID: second.ID, Starred: true, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+			// Starring doesn't touch the read flag that's already there
+			if err := s.SetPostStarred(ctx, database.SetPostStarredParams{UserID: alice.ID, PostID: first.ID, Starred: true, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SetPostStarred(ctx, database.SetPostStarredParams{UserID: alice.ID, PostID: first.ID, Starred: false, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := s.GetPostsWithState(ctx, database.GetPostsWithStateParams{UserID: alice.ID, MaxPosts: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := map[string]string{}
+			for _, row := range rows {
+				state[row.Title] = map[bool]string{true: "read", false: "unread"}[row.Read] + map[bool]string{true: " starred"}[row.Starred]
+			}
+			if state["First"] != "read" || state["Second"] != "unread starred" || state["Third"] != "unread" {
+				t.Errorf("alice's posts are %v", state)
+			}
+
+			starred, err := s.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: alice.ID, StarredOnly: true, MaxPosts: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTitles(t, "alice's starred posts", postTitles(starred, func(r database.GetPostsForUserRow) string { return r.Title }), []string{"Second"})
+
+			for _, tt := range []struct {
+				user database.User
+				want int64
+			}{{alice, 2}, {bob, 3}} {
+				counts, err := s.GetFeedsWithUnreadCount(ctx, tt.user.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(counts) != 1 || counts[0].Unread != tt.want {
+					t.Errorf("%s's unread counts = %+v, want %d", tt.user.Name, counts, tt.want)
+				}
+			}
+		}},
+
+		{"search ignores case", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			feed := dbtest.CreateFeed(t, s, user, "Blog", "https://example.com/feed")
+			other := dbtest.CreateFeed(t, s, user, "News", "https://news.example/feed")
+			dbtest.Follow(t, s, user, feed)
+			dbtest.Follow(t, s, user, other)
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Generics in Go", Day: 1})
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Borrowing", Day: 2})
+			dbtest.CreatePost(t, s, other, dbtest.Post{Title: "Go news", Day: 3})
+
+			searches := []struct {
+				params database.GetPostsWithStateParams
+				want   []string
+			}{
+				{database.GetPostsWithStateParams{Search: "GENERICS"}, []string{"Generics in Go"}},
+				{database.GetPostsWithStateParams{Search: "about borrow"}, []string{"Borrowing"}},
+				{database.GetPostsWithStateParams{Search: "go"}, []string{"Go news", "Generics in Go"}},
+				{database.GetPostsWithStateParams{Search: "go", FeedID: uuid.NullUUID{UUID: feed.ID, Valid: true}}, []string{"Generics in Go"}},
+			}
+			for _, search := range searches {
+				search.params.UserID = user.ID
+				search.params.MaxPosts = 10
+				rows, err := s.GetPostsWithState(ctx, search.params)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkTitles(t, "GetPostsWithState("+search.params.Search+")", postTitles(rows, func(r database.GetPostsWithStateRow) string { return r.Title }), search.want)
+			}
+		}},
+
+		{"posts are found by id prefix among the ones the user follows", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			alice := dbtest.CreateUser(t, s, "alice", false)
+			bob := dbtest.CreateUser(t, s, "bob", false)
+			feed := dbtest.CreateFeed(t, s, alice, "Blog", "https://example.com/feed")
+			dbtest.Follow(t, s, alice, feed)
+			post := dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Hello", Day: 1})
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "World", Day: 2})
+
+			rows, err := s.GetPostsByIDPrefix(ctx, database.GetPostsByIDPrefixParams{UserID: alice.ID, Prefix: post.ID.String()[:13]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTitles(t, "GetPostsByIDPrefix(alice)", postTitles(rows, func(r database.GetPostsByIDPrefixRow) string { return r.Title }), []string{"Hello"})
+
+			rows, err = s.GetPostsByIDPrefix(ctx, database.GetPostsByIDPrefixParams{UserID: bob.ID, Prefix: post.ID.String()[:13]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTitles(t, "GetPostsByIDPrefix(bob)", postTitles(rows, func(r database.GetPostsByIDPrefixRow) string { return r.Title }), []string{})
+		}},
+
+		{"post id neighbours only count followed posts", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			alice := dbtest.CreateUser(t, s, "alice", false)
+			followed := dbtest.CreateFeed(t, s, alice, "Blog", "https://example.com/feed")
+			dbtest.Follow(t, s, alice, followed)
+			other := dbtest.CreateFeed(t, s, alice, "Other", "https://other.example/feed")
+
+			ids := map[string]uuid.UUID{}
+			for _, post := range []struct {
+				id   string
+				feed database.Feed
+			}{
+				{"11111111-0000-4000-8000-000000000000", followed},
+				{"11111111-2222-4000-8000-000000000000", followed},
+				{"11111111-2223-4000-8000-000000000000", followed},
+				{"11111111-2222-5000-8000-000000000000", other},
+			} {
+				id := uuid.MustParse(post.id)
+				ids[post.id] = id
+				_, err := s.CreatePost(ctx, database.CreatePostParams{ID: id, CreatedAt: dbtest.Time, UpdatedAt: dbtest.Time, Title: post.id, Url: "https://example.com/" + post.id, PublishedAt: "2026-10-01T09:00:00Z", FeedID: post.feed.ID})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tests := []struct {
+				id       string
+				previous string
+				next     string
+			}{
+				{"11111111-0000-4000-8000-000000000000", "", "11111111-2222-4000-8000-000000000000"},
+				{"11111111-2222-4000-8000-000000000000", "11111111-0000-4000-8000-000000000000", "11111111-2223-4000-8000-000000000000"},
+				{"11111111-2223-4000-8000-000000000000", "11111111-2222-4000-8000-000000000000", ""},
+			}
+			for _, tt := range tests {
+				row, err := s.GetPostIDNeighbours(ctx, database.GetPostIDNeighboursParams{UserID: alice.ID, ID: ids[tt.id]})
+				if err != nil {
+					t.Fatal(err)
+				}
+				previous, next := "", ""
+				if row.PreviousID.Valid {
+					previous = row.PreviousID.UUID.String()
+				}
+				if row.NextID.Valid {
+					next = row.NextID.UUID.String()
+				}
+				if previous != tt.previous || next != tt.next {
+					t.Errorf("GetPostIDNeighbours(%s) = %q, %q, want %q, %q", tt.id, previous, next, tt.previous, tt.next)
+				}
+			}
+		}},
+
+		{"the least recently fetched active feed is next", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			stale := dbtest.CreateFeed(t, s, user, "Stale", "https://stale.example/feed")
+			fresh := dbtest.CreateFeed(t, s, user, "Fresh", "https://fresh.example/feed")
+			if err := s.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{ID: fresh.ID, LastFetchedAt: dbtest.Time.Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{ID: stale.ID, LastFetchedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+
+			next, err := s.GetNextFeedToFetch(ctx)
+			if err != nil || next.ID != stale.ID {
+				t.Errorf("GetNextFeedToFetch = %+v, %v, want Stale", next, err)
+			}
+
+			if err := s.SetFeedActive(ctx, database.SetFeedActiveParams{ID: stale.ID, Active: false, DeactivatedAt: sql.NullTime{Time: dbtest.Time, Valid: true}, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+			next, err = s.GetNextFeedToFetch(ctx)
+			if err != nil || next.ID != fresh.ID {
+				t.Errorf("GetNextFeedToFetch with Stale inactive = %+v, %v, want Fresh", next, err)
+			}
+		}},
+
+		{"pruning keeps starred posts and remembers the rest", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			feed := dbtest.CreateFeed(t, s, user, "Blog", "https://example.com/feed")
+			dbtest.Follow(t, s, user, feed)
+			oldest := dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Oldest", Day: 1})
+			starred := dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Starred", Day: 2})
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Newer", Day: 3})
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Newest", Day: 4})
+			if err := s.SetPostStarred(ctx, database.SetPostStarredParams{UserID: user.ID, PostID: starred.ID, Starred: true, UpdatedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+
+			ids, err := s.GetPostsToPrune(ctx, database.GetPostsToPruneParams{FeedID: feed.ID, KeepNewest: sql.NullInt64{Int64: 1, Valid: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != 2 || ids[1] != oldest.ID {
+				t.Errorf("GetPostsToPrune(keep 1) = %v, want Newer and Oldest", ids)
+			}
+			ids, err = s.GetPostsToPrune(ctx, database.GetPostsToPruneParams{FeedID: feed.ID, PublishedBefore: sql.NullString{String: "2026-10-03T00:00:00Z", Valid: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != 1 || ids[0] != oldest.ID {
+				t.Errorf("GetPostsToPrune(before the 3rd) = %v, want only Oldest", ids)
+			}
+
+			// An old post fetched today is as old as its publish date
+			backfilled, err := s.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Title: "Backfilled", Url: "https://example.com/backfilled", PublishedAt: "2026-09-01T09:00:00Z", FeedID: feed.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids, err = s.GetPostsToPrune(ctx, database.GetPostsToPruneParams{FeedID: feed.ID, PublishedBefore: sql.NullString{String: "2026-10-03T00:00:00Z", Valid: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != 2 || ids[1] != backfilled.ID {
+				t.Errorf("GetPostsToPrune(before the 3rd) = %v, want Oldest and Backfilled", ids)
+			}
+			if err := s.DeletePost(ctx, backfilled.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.ArchivePost(ctx, database.ArchivePostParams{ID: oldest.ID, ArchivedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.AddPrunedPost(ctx, database.AddPrunedPostParams{ID: oldest.ID, PrunedAt: dbtest.Time}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeletePost(ctx, oldest.ID); err != nil {
+				t.Fatal(err)
+			}
+			if pruned, err := s.IsPostPruned(ctx, oldest.Url); err != nil || !pruned {
+				t.Errorf("IsPostPruned(oldest) = %v, %v, want true", pruned, err)
+			}
+			if pruned, err := s.IsPostPruned(ctx, starred.Url); err != nil || pruned {
+				t.Errorf("IsPostPruned(starred) = %v, %v, want false", pruned, err)
+			}
+			archived, err := s.ListArchivedPosts(ctx)
+			if err != nil || len(archived) != 1 || archived[0].Title != "Oldest" {
+				t.Errorf("ListArchivedPosts = %+v, %v, want Oldest", archived, err)
+			}
+		}},
+
+		{"api tokens find their user", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			token := database.CreateAPITokenParams{ID: uuid.New(), CreatedAt: dbtest.Time, UserID: user.ID, Name: "laptop", TokenHash: "abc123"}
+			if _, err := s.CreateAPIToken(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+			token.ID, token.TokenHash = uuid.New(), "def456"
+			if _, err := s.CreateAPIToken(ctx, token); !database.IsUniqueViolation(err) {
+				t.Errorf("second laptop token: got %v, want a unique violation", err)
+			}
+
+			found, err := s.GetUserByAPIToken(ctx, "abc123")
+			if err != nil || found.ID != user.ID {
+				t.Errorf("GetUserByAPIToken = %+v, %v, want alice", found, err)
+			}
+			if rows, err := s.DeleteAPIToken(ctx, database.DeleteAPITokenParams{UserID: user.ID, Name: "laptop"}); err != nil || rows != 1 {
+				t.Errorf("DeleteAPIToken = %d, %v, want 1 row", rows, err)
+			}
+			if _, err := s.GetUserByAPIToken(ctx, "abc123"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetUserByAPIToken after revoking error = %v, want sql.ErrNoRows", err)
+			}
+		}},
+
+		{"web sessions last until they expire or their token is revoked", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			user := dbtest.CreateUser(t, s, "alice", false)
+			token, err := s.CreateAPIToken(ctx, database.CreateAPITokenParams{ID: uuid.New(), CreatedAt: dbtest.Time, UserID: user.ID, Name: "laptop", TokenHash: "abc123"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found, err := s.GetAPIToken(ctx, "abc123"); err != nil || found.ID != token.ID {
+				t.Errorf("GetAPIToken = %+v, %v, want laptop", found, err)
+			}
+			for hash, expires := range map[string]time.Time{"current": dbtest.Time.Add(time.Hour), "expired": dbtest.Time, "other": dbtest.Time.Add(time.Hour)} {
+				_, err := s.CreateWebSession(ctx, database.CreateWebSessionParams{ID: uuid.New(), CreatedAt: dbtest.Time, ExpiresAt: expires, ApiTokenID: token.ID, TokenHash: hash})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			session := func(hash string) database.GetUserByWebSessionParams {
+				return database.GetUserByWebSessionParams{TokenHash: hash, ExpiresAt: dbtest.Time}
+			}
+			if found, err := s.GetUserByWebSession(ctx, session("current")); err != nil || found.ID != user.ID {
+				t.Errorf("GetUserByWebSession(current) = %+v, %v, want alice", found, err)
+			}
+			if _, err := s.GetUserByWebSession(ctx, session("expired")); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetUserByWebSession(expired) error = %v, want sql.ErrNoRows", err)
+			}
+			if rows, err := s.DeleteExpiredWebSessions(ctx, dbtest.Time); err != nil || rows != 1 {
+				t.Errorf("DeleteExpiredWebSessions = %d, %v, want 1 row", rows, err)
+			}
+
+			for _, want := range []int64{1, 0} {
+				if rows, err := s.DeleteWebSession(ctx, "current"); err != nil || rows != want {
+					t.Errorf("DeleteWebSession(current) = %d, %v, want %d rows", rows, err, want)
+				}
+			}
+			if _, err := s.GetUserByWebSession(ctx, session("current")); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetUserByWebSession after logging out error = %v, want sql.ErrNoRows", err)
+			}
+
+			if _, err := s.DeleteAPIToken(ctx, database.DeleteAPITokenParams{UserID: user.ID, Name: "laptop"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.GetUserByWebSession(ctx, session("other")); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetUserByWebSession after revoking its token error = %v, want sql.ErrNoRows", err)
+			}
+		}},
+
+		{"deleting a user removes everything they own", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			alice := dbtest.CreateUser(t, s, "alice", false)
+			bob := dbtest.CreateUser(t, s, "bob", false)
+			feed := dbtest.CreateFeed(t, s, alice, "Blog", "https://example.com/feed")
+			kept := dbtest.CreateFeed(t, s, bob, "News", "https://news.example/feed")
+			dbtest.Follow(t, s, alice, kept)
+			dbtest.Follow(t, s, bob, kept)
+			dbtest.CreatePost(t, s, feed, dbtest.Post{Title: "Hello", Day: 1, Authors: []string{"Alice"}, Categories: []string{"Intro"}})
+			dbtest.CreatePost(t, s, kept, dbtest.Post{Title: "Headlines", Day: 2})
+			if _, err := s.CreateAPIToken(ctx, database.CreateAPITokenParams{ID: uuid.New(), CreatedAt: dbtest.Time, UserID: alice.ID, Name: "laptop", TokenHash: "abc123"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if rows, err := s.DeleteUser(ctx, "alice"); err != nil || rows != 1 {
+				t.Fatalf("DeleteUser(alice) = %d, %v, want 1 row", rows, err)
+			}
+
+			feeds, err := s.ListFeeds(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTitles(t, "feeds left", postTitles(feeds, func(f database.Feed) string { return f.Name }), []string{"News"})
+			posts, err := s.ListPosts(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTitles(t, "posts left", postTitles(posts, func(p database.Post) string { return p.Title }), []string{"Headlines"})
+			for name, count := range map[string]func() (int, error){
+				"follows": func() (int, error) { rows, err := s.ListFeedFollows(ctx); return len(rows), err },
+				"authors": func() (int, error) { rows, err := s.ListPostAuthors(ctx); return len(rows), err },
+				"tokens":  func() (int, error) { rows, err := s.GetAPITokensForUser(ctx, alice.ID); return len(rows), err },
+			} {
+				want := map[string]int{"follows": 1}[name]
+				if n, err := count(); err != nil || n != want {
+					t.Errorf("%s left = %d, %v, want %d", name, n, err, want)
+				}
+			}
+		}},
+
+		{"feeds are handed to another user", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			alice := dbtest.CreateUser(t, s, "alice", false)
+			bob := dbtest.CreateUser(t, s, "bob", false)
+			dbtest.CreateFeed(t, s, bob, "One", "https://one.example/feed")
+			dbtest.CreateFeed(t, s, bob, "Two", "https://two.example/feed")
+			dbtest.CreateFeed(t, s, alice, "Three", "https://three.example/feed")
+
+			rows, err := s.ReassignFeeds(ctx, database.ReassignFeedsParams{NewUserID: alice.ID, UpdatedAt: dbtest.Time, OldUserID: bob.ID})
+			if err != nil || rows != 2 {
+				t.Fatalf("ReassignFeeds = %d, %v, want 2 rows", rows, err)
+			}
+			if _, err := s.DeleteUser(ctx, "bob"); err != nil {
+				t.Fatal(err)
+			}
+			feeds, err := s.ListFeeds(ctx)
+			if err != nil || len(feeds) != 3 {
+				t.Fatalf("feeds after deleting bob = %+v, %v, want all three", feeds, err)
+			}
+			for _, feed := range feeds {
+				if feed.UserID != alice.ID {
+					t.Errorf("%s belongs to %s, want alice", feed.Name, feed.UserID)
+				}
+			}
+		}},
+
+		{"a failed transaction leaves nothing behind", func(t *testing.T, s database.Store) {
+			ctx := context.Background()
+			failed := errors.New("failed")
+			err := s.InTx(ctx, func(tx database.Store) error {
+				user := dbtest.CreateUser(t, tx, "alice", false)
+				dbtest.CreateFeed(t, tx, user, "Blog", "https://example.com/feed")
+				return failed
+			})
+			if !errors.Is(err, failed) {
+				t.Errorf("InTx error = %v, want %v", err, failed)
+			}
+			if users, err := s.GetUsers(ctx); err != nil || len(users) != 0 {
+				t.Errorf("users after rollback = %v, %v", users, err)
+			}
+			if feeds, err := s.ListFeeds(ctx); err != nil || len(feeds) != 0 {
+				t.Errorf("feeds after rollback = %+v, %v", feeds, err)
+			}
+
+			err = s.InTx(ctx, func(tx database.Store) error {
+				dbtest.CreateUser(t, tx, "alice", false)
+				return nil
+			})
+			if users, _ := s.GetUsers(ctx); err != nil || len(users) != 1 {
+				t.Errorf("users after commit = %v, %v", users, err)
+			}
+		}},
+	}
+
+	for _, backend := range dbtest.Backends(t) {
+		for _, tt := range tests {
+			t.Run(backend.Name+"/"+tt.name, func(t *testing.T) {
+				tt.run(t, backend.Open(t))
+			})
+		}
+	}
+}
@@ -13,14 +13,15 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, is_admin
 `
 
 type CreateUserParams struct {
@@ -28,6 +29,7 @@ type CreateUserParams struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	IsAdmin   bool
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -36,6 +38,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.IsAdmin,
 	)
 	var i User
 	err := row.Scan(
@@ -43,12 +46,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, is_admin FROM users
 WHERE name = $1
 `
 
@@ -60,6 +64,7 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.IsAdmin,
 	)
 	return i, err
 }
@@ -91,11 +96,45 @@ func (q *Queries) GetUsers(ctx context.Context) ([]string, error) {
 	return items, nil
 }
 
+const setUserAdmin = `-- name: SetUserAdmin :execrows
+UPDATE users SET
+    is_admin = $2,
+    updated_at = $3
+WHERE name = $1
+`
+
+type SetUserAdminParams struct {
+	Name      string
+	IsAdmin   bool
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserAdmin, arg.Name, arg.IsAdmin, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const resetUsers = `-- name: ResetUsers :exec
-DELETE FROM users CASCADE;
+DELETE FROM users
 `
 
 func (q *Queries) ResetUsers(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetUsers)
 	return err
 }
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE name = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
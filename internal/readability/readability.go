@@ -0,0 +1,208 @@
+package readability
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text`)
+	negativeHint = regexp.MustCompile(`(?i)ad-|advert|banner|combx|comment|footer|footnote|menu|meta|nav|popup|promo|related|share|sidebar|social|sponsor|subscribe|widget`)
+
+	// Elements that never hold the article body
+	junkElements = map[atom.Atom]bool{
+		atom.Script:   true,
+		atom.Style:    true,
+		atom.Noscript: true,
+		atom.Iframe:   true,
+		atom.Form:     true,
+		atom.Button:   true,
+		atom.Nav:      true,
+		atom.Header:   true,
+		atom.Footer:   true,
+		atom.Aside:    true,
+		atom.Svg:      true,
+	}
+
+	// Elements that can be picked as the article container
+	candidateElements = map[atom.Atom]bool{
+		atom.Div:        true,
+		atom.Section:    true,
+		atom.Article:    true,
+		atom.Main:       true,
+		atom.Td:         true,
+		atom.Blockquote: true,
+	}
+)
+
+var ErrNoContent = errors.New("no article content found")
+
+// Reads an html page and returns the html of the element most likely to hold the article body
+func Extract(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return "", ErrNoContent
+	}
+
+	removeJunk(body)
+
+	best := bestCandidate(body)
+	if best == nil {
+		return "", ErrNoContent
+	}
+
+	var buf bytes.Buffer
+	for child := best.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&buf, child); err != nil {
+			return "", err
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// Scores every paragraph's parent and grandparent and returns the highest scoring node
+func bestCandidate(body *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+
+	walk(body, func(n *html.Node) {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre {
+			return
+		}
+		text := textContent(n)
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ","))
+		score += min(float64(len(text))/100, 3)
+
+		if parent := n.Parent; parent != nil && candidateElements[parent.DataAtom] {
+			scores[parent] += score
+			if grandparent := parent.Parent; grandparent != nil && candidateElements[grandparent.DataAtom] {
+				scores[grandparent] += score / 2
+			}
+		}
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, score := range scores {
+		score += classWeight(n)
+		score *= 1 - linkDensity(n)
+		if score > bestScore {
+			best = n
+			bestScore = score
+		}
+	}
+
+	// Pages without scorable paragraphs can still mark their content with <article>
+	if best == nil {
+		best = findFirst(body, atom.Article)
+	}
+
+	return best
+}
+
+// Rewards class and id names that look like content and punishes ones that look like chrome
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeHint.MatchString(name) {
+			weight -= 25
+		}
+		if positiveHint.MatchString(name) {
+			weight += 25
+		}
+	}
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		weight += 10
+	}
+	return weight
+}
+
+// Returns the fraction of a node's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	walk(n, func(child *html.Node) {
+		if child.DataAtom == atom.A {
+			linked += len(textContent(child))
+		}
+	})
+	return float64(linked) / float64(total)
+}
+
+// Removes scripts, navigation and other chrome, along with blocks whose class or id marks them as such
+func removeJunk(n *html.Node) {
+	var next *html.Node
+	for child := n.FirstChild; child != nil; child = next {
+		next = child.NextSibling
+		if child.Type == html.CommentNode || junkElements[child.DataAtom] || isUnlikelyCandidate(child) {
+			n.RemoveChild(child)
+			continue
+		}
+		removeJunk(child)
+	}
+}
+
+func isUnlikelyCandidate(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	hints := attr(n, "class") + " " + attr(n, "id")
+	return negativeHint.MatchString(hints) && !positiveHint.MatchString(hints)
+}
+
+func walk(n *html.Node, f func(*html.Node)) {
+	f(n)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, f)
+	}
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(child *html.Node) {
+		if found == nil && child.DataAtom == a {
+			found = child
+		}
+	})
+	return found
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(child *html.Node) {
+		if child.Type == html.TextNode {
+			sb.WriteString(child.Data)
+		}
+	})
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
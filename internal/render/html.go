@@ -0,0 +1,145 @@
+package render
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/Daxin319/Gator/internal/sanitize"
+)
+
+// Renders a markdown document as an html fragment for the web reader. It understands the
+// same markdown as Markdown, escapes all text, and drops links that aren't http, https or
+// mailto. Images are shown as links, like in the terminal, so reading a post doesn't load
+// anything from the sites it came from.
+func HTML(markdown string) string {
+	var out strings.Builder
+	var paragraph []string
+	list := ""
+
+	closeList := func() {
+		if list != "" {
+			out.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	flush := func() {
+		closeList()
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString("<p>" + spansHTML(parseInline(strings.Join(paragraph, " "))) + "</p>\n")
+		paragraph = nil
+	}
+
+	lines := strings.Split(sanitize.Text(strings.ReplaceAll(markdown, "\r\n", "\n")), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			out.WriteString("<pre><code>")
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				out.WriteString(html.EscapeString(lines[i]) + "\n")
+			}
+			out.WriteString("</code></pre>\n")
+
+		case isRule(trimmed):
+			flush()
+			out.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			text := strings.TrimSpace(trimmed[level:])
+			if level > 6 || text == "" {
+				paragraph = append(paragraph, trimmed)
+				continue
+			}
+			tag := "h" + string(rune('0'+level))
+			out.WriteString("<" + tag + ">" + spansHTML(parseInline(text)) + "</" + tag + ">\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			out.WriteString("<blockquote><p>" + spansHTML(parseInline(strings.Join(quote, " "))) + "</p></blockquote>\n")
+
+		default:
+			if marker, text, ok := listItem(line); ok {
+				if len(paragraph) > 0 {
+					flush()
+				}
+				kind := "ol"
+				if marker == "•" {
+					kind = "ul"
+				}
+				if kind != list {
+					closeList()
+					out.WriteString("<" + kind + ">\n")
+					list = kind
+				}
+				out.WriteString("<li>" + spansHTML(parseInline(text)) + "</li>\n")
+				continue
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return out.String()
+}
+
+// Writes out spans as escaped html, with each run of spans that share a link inside one <a>
+func spansHTML(spans []span) string {
+	var out strings.Builder
+	link := ""
+	for _, s := range spans {
+		if s.link != link {
+			if link != "" {
+				out.WriteString("</a>")
+			}
+			link = ""
+			if safeLink(s.link) {
+				link = s.link
+				out.WriteString(`<a href="` + html.EscapeString(link) + `" rel="noopener noreferrer" target="_blank">`)
+			}
+		}
+
+		text := html.EscapeString(s.text)
+		if s.code {
+			text = "<code>" + text + "</code>"
+		}
+		if s.italic {
+			text = "<em>" + text + "</em>"
+		}
+		if s.bold {
+			text = "<strong>" + text + "</strong>"
+		}
+		out.WriteString(text)
+	}
+	if link != "" {
+		out.WriteString("</a>")
+	}
+	return out.String()
+}
+
+func safeLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
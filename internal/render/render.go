@@ -0,0 +1,562 @@
+package render
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Daxin319/Gator/internal/sanitize"
+	"golang.org/x/term"
+)
+
+const (
+	reset     = "\x1b[0m"
+	bold      = "\x1b[1m"
+	dim       = "\x1b[2m"
+	italic    = "\x1b[3m"
+	underline = "\x1b[4m"
+	cyan      = "\x1b[36m"
+	blue      = "\x1b[34m"
+	magenta   = "\x1b[35m"
+
+	defaultWidth = 80
+	maxWidth     = 120
+)
+
+// Renders markdown for a terminal, either styled with ANSI escapes or as plain text. Control
+// characters in the text are dropped, so feeds can't slip their own escape sequences in.
+type Renderer struct {
+	Width int
+	Color bool
+}
+
+// Returns a renderer for w. Styling is turned off when w isn't a terminal or NO_COLOR is set,
+// and the width comes from $COLUMNS, then the terminal size, then a default of 80.
+func For(w io.Writer) Renderer {
+	f, isFile := w.(*os.File)
+	isTerminal := isFile && term.IsTerminal(int(f.Fd()))
+
+	width := 0
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		width = columns
+	} else if isTerminal {
+		if columns, _, err := term.GetSize(int(f.Fd())); err == nil {
+			width = columns
+		}
+	}
+	if width <= 0 {
+		width = defaultWidth
+	}
+
+	_, noColor := os.LookupEnv("NO_COLOR")
+
+	return Renderer{
+		Width: width,
+		Color: isTerminal && !noColor,
+	}
+}
+
+// Returns a horizontal line across the full width
+func (r Renderer) Rule() string {
+	return r.style(strings.Repeat("─", r.Width), dim) + "\n"
+}
+
+// Returns text as a heading wrapped to the renderer's width
+func (r Renderer) Heading(text string) string {
+	return r.wrap(parseInline(sanitize.Text(text)), "", "", bold+magenta)
+}
+
+// Returns text dimmed, for metadata lines such as dates and bylines
+func (r Renderer) Faint(text string) string {
+	return r.style(sanitize.Text(text), dim)
+}
+
+// Returns a hyperlink to url labelled text. Without color it falls back to "text (url)".
+func (r Renderer) Link(text, url string) string {
+	text, url = sanitize.Text(text), sanitize.Text(url)
+	if !r.Color {
+		if text == "" || text == url {
+			return url
+		}
+		return text + " (" + url + ")"
+	}
+	return hyperlink(r.style(text, underline+blue), url)
+}
+
+// Renders a markdown document, word-wrapping paragraphs, lists and quotes to the renderer's width
+func (r Renderer) Markdown(markdown string) string {
+	var out strings.Builder
+	var paragraph []string
+	inList := false
+
+	flush := func() {
+		if inList {
+			out.WriteString("\n")
+			inList = false
+		}
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString(r.wrap(parseInline(strings.Join(paragraph, " ")), "", "", ""))
+		out.WriteString("\n")
+		paragraph = nil
+	}
+
+	lines := strings.Split(sanitize.Text(strings.ReplaceAll(markdown, "\r\n", "\n")), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				out.WriteString("    " + r.style(lines[i], cyan) + "\n")
+			}
+			out.WriteString("\n")
+
+		case isRule(trimmed):
+			flush()
+			out.WriteString(r.Rule() + "\n")
+
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			text := strings.TrimSpace(trimmed[level:])
+			if level > 6 || text == "" {
+				paragraph = append(paragraph, trimmed)
+				continue
+			}
+			out.WriteString(r.wrap(parseInline(text), "", "", bold+magenta))
+			out.WriteString("\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			bar := r.style("│ ", dim)
+			out.WriteString(r.wrap(parseInline(strings.Join(quote, " ")), bar, bar, italic))
+			out.WriteString("\n")
+
+		default:
+			if marker, text, ok := listItem(line); ok {
+				// Consecutive items stay together, only a paragraph before the list is flushed
+				if len(paragraph) > 0 {
+					flush()
+				}
+				indent := strings.Repeat(" ", len(line)-len(strings.TrimLeft(line, " ")))
+				first := indent + marker + " "
+				rest := strings.Repeat(" ", utf8.RuneCountInString(first))
+				out.WriteString(r.wrap(parseInline(text), first, rest, ""))
+				inList = true
+				continue
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return strings.TrimRight(out.String(), "\n") + "\n"
+}
+
+// Lays out spans as words, breaking lines before they pass the width.
+// firstPrefix starts the first line and prefix starts every following one.
+func (r Renderer) wrap(spans []span, firstPrefix, prefix, base string) string {
+	width := r.Width
+	if width > maxWidth {
+		width = maxWidth
+	}
+
+	var out strings.Builder
+	out.WriteString(firstPrefix)
+	lineLength := visibleLength(firstPrefix)
+	lineStart := true
+
+	for _, w := range words(spans, r.Color) {
+		length := w.length()
+		if !lineStart && lineLength+1+length > width {
+			out.WriteString("\n" + prefix)
+			lineLength = visibleLength(prefix)
+			lineStart = true
+		}
+		if !lineStart {
+			out.WriteString(" ")
+			lineLength++
+		}
+		out.WriteString(w.render(r, base))
+		lineLength += length
+		lineStart = false
+	}
+	out.WriteString("\n")
+
+	return out.String()
+}
+
+func (r Renderer) style(text, codes string) string {
+	if !r.Color || codes == "" || text == "" {
+		return text
+	}
+	return codes + text + reset
+}
+
+// Wraps text in an OSC 8 escape so terminals make it clickable
+func hyperlink(text, url string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// A run of text sharing the same inline style
+type span struct {
+	text   string
+	bold   bool
+	italic bool
+	code   bool
+	link   string
+}
+
+func (s span) codes() string {
+	var codes string
+	if s.bold {
+		codes += bold
+	}
+	if s.italic {
+		codes += italic
+	}
+	if s.code {
+		codes += cyan
+	}
+	if s.link != "" {
+		codes += underline + blue
+	}
+	return codes
+}
+
+// A word is one or more pieces of styled text with no space between them, like "**bold**,"
+type word []span
+
+func (w word) length() int {
+	length := 0
+	for _, piece := range w {
+		length += utf8.RuneCountInString(piece.text)
+	}
+	return length
+}
+
+func (w word) render(r Renderer, base string) string {
+	var out strings.Builder
+	for _, piece := range w {
+		text := r.style(piece.text, base+piece.codes())
+		if r.Color && piece.link != "" {
+			text = hyperlink(text, piece.link)
+		}
+		out.WriteString(text)
+	}
+	return out.String()
+}
+
+// Splits spans on whitespace. Without color, links get their url appended as a word of its own.
+func words(spans []span, color bool) []word {
+	var result []word
+	var current word
+
+	endWord := func() {
+		if len(current) > 0 {
+			result = append(result, current)
+			current = nil
+		}
+	}
+
+	for i, s := range spans {
+		runes := []rune(s.text)
+		if len(runes) > 0 && unicode.IsSpace(runes[0]) {
+			endWord()
+		}
+		for j, field := range strings.FieldsFunc(s.text, unicode.IsSpace) {
+			if j > 0 {
+				endWord()
+			}
+			piece := s
+			piece.text = field
+			current = append(current, piece)
+		}
+		if len(runes) > 0 && unicode.IsSpace(runes[len(runes)-1]) {
+			endWord()
+		}
+
+		// The url follows the link text once it ends so it can still be copied
+		lastOfLink := i+1 == len(spans) || spans[i+1].link != s.link
+		if !color && s.link != "" && lastOfLink && spansText(spans, s.link, i+1) != s.link {
+			endWord()
+			result = append(result, word{{text: "(" + s.link + ")"}})
+		}
+	}
+	endWord()
+
+	return result
+}
+
+// Returns the text of the run of spans linking to link that ends just before index end
+func spansText(spans []span, link string, end int) string {
+	start := end
+	for start > 0 && spans[start-1].link == link {
+		start--
+	}
+	var text strings.Builder
+	for _, s := range spans[start:end] {
+		text.WriteString(s.text)
+	}
+	return text.String()
+}
+
+// Parses emphasis, inline code, links and images out of a line of markdown
+func parseInline(text string) []span {
+	var spans []span
+	var current strings.Builder
+	state := span{}
+
+	emit := func() {
+		if current.Len() > 0 {
+			s := state
+			s.text = current.String()
+			spans = append(spans, s)
+			current.Reset()
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		switch {
+		case c == '\\' && i+1 < len(runes) && (unicode.IsPunct(runes[i+1]) || unicode.IsSymbol(runes[i+1])):
+			current.WriteRune(runes[i+1])
+			i++
+
+		case c == '`':
+			end := indexRune(runes, '`', i+1)
+			if end < 0 {
+				current.WriteRune(c)
+				continue
+			}
+			emit()
+			spans = append(spans, span{text: string(runes[i+1 : end]), code: true, link: state.link})
+			i = end
+
+		case c == '*' && i+1 < len(runes) && runes[i+1] == '*', c == '_' && i+1 < len(runes) && runes[i+1] == '_':
+			emit()
+			state.bold = !state.bold
+			i++
+
+		case (c == '*' || c == '_') && isEmphasisBoundary(runes, i, state.italic):
+			emit()
+			state.italic = !state.italic
+
+		case c == '!' && i+1 < len(runes) && runes[i+1] == '[':
+			label, url, end, ok := parseLink(runes, i+1)
+			if !ok {
+				current.WriteRune(c)
+				continue
+			}
+			emit()
+			if label == "" {
+				label = "image"
+			}
+			spans = append(spans, span{text: "[" + label + "]", link: url})
+			i = end
+
+		case c == '[':
+			label, url, end, ok := parseLink(runes, i)
+			if !ok {
+				current.WriteRune(c)
+				continue
+			}
+			emit()
+			for _, s := range parseInline(label) {
+				s.bold = s.bold || state.bold
+				s.italic = s.italic || state.italic
+				s.link = url
+				spans = append(spans, s)
+			}
+			i = end
+
+		default:
+			current.WriteRune(c)
+		}
+	}
+	emit()
+
+	return spans
+}
+
+// Parses [label](url) starting at the opening bracket and returns the index of the closing paren
+func parseLink(runes []rune, start int) (string, string, int, bool) {
+	depth := 0
+	closeBracket := -1
+	for i := start; i < len(runes); i++ {
+		if runes[i] == '\\' {
+			i++
+			continue
+		}
+		if runes[i] == '[' {
+			depth++
+		} else if runes[i] == ']' {
+			depth--
+			if depth == 0 {
+				closeBracket = i
+				break
+			}
+		}
+	}
+	if closeBracket < 0 || closeBracket+1 >= len(runes) || runes[closeBracket+1] != '(' {
+		return "", "", 0, false
+	}
+
+	closeParen := indexRune(runes, ')', closeBracket+2)
+	if closeParen < 0 {
+		return "", "", 0, false
+	}
+
+	url := strings.TrimSpace(string(runes[closeBracket+2 : closeParen]))
+	// Drop an optional title: [label](url "title")
+	if space := strings.IndexByte(url, ' '); space >= 0 {
+		url = url[:space]
+	}
+	url = strings.Trim(url, "<>")
+
+	return string(runes[start+1 : closeBracket]), url, closeParen, true
+}
+
+// Single * and _ only count as emphasis at the edge of a word, so snake_case stays intact
+func isEmphasisBoundary(runes []rune, i int, closing bool) bool {
+	if closing {
+		return i > 0 && !unicode.IsSpace(runes[i-1]) && (i+1 == len(runes) || !isWordRune(runes[i+1]))
+	}
+	return i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && (i == 0 || !isWordRune(runes[i-1]))
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func indexRune(runes []rune, target rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// Matches markdown list items like "- item", "* item" and "1. item"
+func listItem(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(trimmed) > 2 && (trimmed[0] == '-' || trimmed[0] == '*' || trimmed[0] == '+') && trimmed[1] == ' ' {
+		return "•", strings.TrimSpace(trimmed[2:]), true
+	}
+
+	digits := 0
+	for digits < len(trimmed) && trimmed[digits] >= '0' && trimmed[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits+1 < len(trimmed) && (trimmed[digits] == '.' || trimmed[digits] == ')') && trimmed[digits+1] == ' ' {
+		return trimmed[:digits+1], strings.TrimSpace(trimmed[digits+2:]), true
+	}
+
+	return "", "", false
+}
+
+func isRule(line string) bool {
+	compact := strings.ReplaceAll(line, " ", "")
+	if len(compact) < 3 {
+		return false
+	}
+	for _, marker := range []string{"-", "*", "_"} {
+		if strings.Trim(compact, marker) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Counts the runes that show up on screen, skipping CSI and OSC escape sequences
+func visibleLength(text string) int {
+	runes := []rune(text)
+	length := 0
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\x1b' {
+			i = escapeEnd(runes, i)
+			continue
+		}
+		length++
+	}
+	return length
+}
+
+// Truncates or pads text to exactly width columns, keeping escape sequences intact
+// and closing any style or hyperlink that was cut off
+func Fit(text string, width int) string {
+	runes := []rune(text)
+	var out strings.Builder
+	length := 0
+	styled := false
+	linkOpen := false
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\x1b' {
+			end := escapeEnd(runes, i)
+			sequence := string(runes[i : end+1])
+			if strings.HasPrefix(sequence, "\x1b]8;") {
+				linkOpen = !strings.HasPrefix(sequence, "\x1b]8;;\x1b")
+			} else {
+				styled = sequence != reset
+			}
+			out.WriteString(sequence)
+			i = end
+			continue
+		}
+		if length == width {
+			break
+		}
+		out.WriteRune(runes[i])
+		length++
+	}
+
+	if linkOpen {
+		out.WriteString("\x1b]8;;\x1b\\")
+	}
+	if styled {
+		out.WriteString(reset)
+	}
+	out.WriteString(strings.Repeat(" ", width-length))
+
+	return out.String()
+}
+
+// Returns the index of the last rune of the escape sequence starting at i
+func escapeEnd(runes []rune, i int) int {
+	if i+1 >= len(runes) {
+		return i
+	}
+	switch runes[i+1] {
+	case '[':
+		for i += 2; i < len(runes) && (runes[i] < '@' || runes[i] > '~'); i++ {
+		}
+	case ']':
+		for i += 2; i < len(runes) && runes[i] != '\a' && !(runes[i] == '\x1b' && i+1 < len(runes) && runes[i+1] == '\\'); i++ {
+		}
+		if i < len(runes) && runes[i] == '\x1b' {
+			i++
+		}
+	default:
+		i++
+	}
+	return min(i, len(runes)-1)
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+// An OSC 52 sequence writes to the clipboard, the kind of thing a feed could hide in a title
+const clipboardWrite = "\x1b]52;c;ZXZpbA==\x07"
+
+func TestRendererDropsControlCharacters(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func(r Renderer) string
+	}{
+		{"heading", func(r Renderer) string { return r.Heading("Hi " + clipboardWrite + " there") }},
+		{"faint", func(r Renderer) string { return r.Faint("by Jane" + clipboardWrite) }},
+		{"link text", func(r Renderer) string { return r.Link("click"+clipboardWrite, "https://example.com") }},
+		{"link url", func(r Renderer) string { return r.Link("click", "https://example.com/"+clipboardWrite) }},
+		{"markdown", func(r Renderer) string {
+			return r.Markdown("# Title" + clipboardWrite + "\n\nSome \x1b[2J**text**\u009b31m\n\n- item\x1b[H\n\n```\ncode\x1b]0;title\x07\n```")
+		}},
+		{"html", func(r Renderer) string { return HTML("Some " + clipboardWrite + " text") }},
+	}
+
+	for _, tt := range tests {
+		for _, color := range []bool{false, true} {
+			got := tt.render(Renderer{Width: 80, Color: color})
+			if strings.Contains(got, "\x1b]52") || strings.Contains(got, "\x1b[2J") || strings.Contains(got, "\x1b[H") ||
+				strings.Contains(got, "\x1b]0;") || strings.ContainsAny(got, "\x07\u009b") {
+				t.Errorf("%s (color %v) let a control sequence through: %q", tt.name, color, got)
+			}
+			if !color && strings.Contains(got, "\x1b") {
+				t.Errorf("%s without color contains an escape: %q", tt.name, got)
+			}
+		}
+	}
+}
+
+func TestRendererKeepsText(t *testing.T) {
+	r := Renderer{Width: 80}
+	got := r.Heading("Hi " + clipboardWrite + " there")
+	if want := "Hi ]52;c;ZXZpbA== there\n"; got != want {
+		t.Errorf("Heading = %q, want %q", got, want)
+	}
+}
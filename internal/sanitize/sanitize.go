@@ -0,0 +1,310 @@
+package sanitize
+
+import (
+	"bytes"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Elements that are kept, along with the attributes each one may carry
+var allowedElements = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Cite:       nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title"},
+	atom.Ins:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          nil,
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Time:       {"datetime"},
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// Elements that are dropped together with everything inside them.
+// Anything else that isn't allowed is unwrapped so its text survives.
+var droppedElements = map[atom.Atom]bool{
+	atom.Applet:   true,
+	atom.Audio:    true,
+	atom.Button:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Head:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Math:     true,
+	atom.Meta:     true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+	atom.Video:    true,
+}
+
+// Hosts and paths that only serve tracking pixels and share-button images
+var trackerPatterns = []string{
+	"doubleclick.net",
+	"feeds.feedburner.com/~r/",
+	"feeds.feedburner.com/~ff/",
+	"feedsportal.com",
+	"google-analytics.com",
+	"pixel.wp.com",
+	"stats.wordpress.com",
+	"pixel.quantserve.com",
+	"sb.scorecardresearch.com",
+	"/open-tracking/",
+	"/track/open",
+	"substackcdn.com/open",
+}
+
+// Strips everything but a small set of formatting elements from an html fragment.
+// Relative links and images are resolved against baseURL, and tracking pixels are removed.
+func HTML(input string, baseURL string) string {
+	if strings.TrimSpace(input) == "" {
+		return ""
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(input), context)
+	if err != nil {
+		return ""
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	clean(root, base)
+
+	var buf bytes.Buffer
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&buf, child); err != nil {
+			return ""
+		}
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// Removes C0 and C1 control characters, apart from newlines and tabs, from text taken out of a
+// feed. A terminal would otherwise act on escape sequences hidden in a title or a post, from
+// changing colours to writing to the clipboard.
+func Text(input string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, input)
+}
+
+func clean(n *html.Node, base *url.URL) {
+	var next *html.Node
+	for child := n.FirstChild; child != nil; child = next {
+		next = child.NextSibling
+
+		switch child.Type {
+		case html.TextNode:
+			child.Data = Text(child.Data)
+			continue
+		case html.ElementNode:
+		default:
+			n.RemoveChild(child)
+			continue
+		}
+
+		if droppedElements[child.DataAtom] {
+			n.RemoveChild(child)
+			continue
+		}
+
+		allowed, ok := allowedElements[child.DataAtom]
+		if !ok {
+			// Clean the children first so the ones moved up are already safe
+			clean(child, base)
+			unwrap(child)
+			continue
+		}
+
+		child.Attr = filterAttributes(child, allowed, base)
+
+		switch child.DataAtom {
+		case atom.Img:
+			if getAttr(child, "src") == "" || isTracker(child) {
+				n.RemoveChild(child)
+				continue
+			}
+			dropSize(child)
+		case atom.A:
+			if getAttr(child, "href") == "" {
+				clean(child, base)
+				unwrap(child)
+				continue
+			}
+		}
+
+		clean(child, base)
+	}
+}
+
+func filterAttributes(n *html.Node, allowed []string, base *url.URL) []html.Attribute {
+	var attrs []html.Attribute
+	for _, a := range n.Attr {
+		if a.Namespace != "" || !slices.Contains(allowed, a.Key) {
+			continue
+		}
+		switch a.Key {
+		case "href", "src":
+			resolved, ok := resolveURL(a.Val, base, a.Key == "href")
+			if !ok {
+				continue
+			}
+			a.Val = resolved
+		case "colspan", "rowspan", "start":
+			if _, err := strconv.Atoi(a.Val); err != nil {
+				continue
+			}
+		}
+		attrs = append(attrs, a)
+	}
+
+	// Tracking pixels announce themselves with their size, keep it around just long enough to check
+	if n.DataAtom == atom.Img {
+		for _, a := range n.Attr {
+			if a.Key == "width" || a.Key == "height" {
+				attrs = append(attrs, a)
+			}
+		}
+	}
+
+	return attrs
+}
+
+// Resolves ref against base and only lets through web and mail links
+func resolveURL(ref string, base *url.URL, allowMailto bool) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+	if !u.IsAbs() {
+		if base == nil {
+			return "", false
+		}
+		u = base.ResolveReference(u)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.String(), true
+	case "mailto":
+		return u.String(), allowMailto
+	default:
+		return "", false
+	}
+}
+
+func isTracker(img *html.Node) bool {
+	for _, key := range []string{"width", "height"} {
+		if size, err := strconv.Atoi(strings.TrimSuffix(getAttr(img, key), "px")); err == nil && size <= 1 {
+			return true
+		}
+	}
+
+	src := strings.ToLower(getAttr(img, "src"))
+	for _, pattern := range trackerPatterns {
+		if strings.Contains(src, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Drops the size attributes that were only kept for the tracker check
+func dropSize(img *html.Node) {
+	var attrs []html.Attribute
+	for _, a := range img.Attr {
+		if a.Key != "width" && a.Key != "height" {
+			attrs = append(attrs, a)
+		}
+	}
+	img.Attr = attrs
+}
+
+// Replaces n with its children
+func unwrap(n *html.Node) {
+	parent := n.Parent
+	for child := n.FirstChild; child != nil; child = n.FirstChild {
+		n.RemoveChild(child)
+		parent.InsertBefore(child, n)
+	}
+	parent.RemoveChild(n)
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
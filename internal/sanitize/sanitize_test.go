@@ -0,0 +1,147 @@
+package sanitize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		baseURL string
+		want    []string
+		notWant []string
+	}{
+		{
+			fixture: "wordpress.html",
+			baseURL: "https://blog.example.com/2026/10/v2/",
+			want: []string{
+				`<strong>version 2</strong>`,
+				`<a href="https://blog.example.com/2026/10/release-notes/">release notes</a>`,
+				`<a href="https://blog.example.com/2026/10/v2/docs/upgrade.html">upgrade guide</a>`,
+				`<img src="https://blog.example.com/wp-content/uploads/2026/10/screenshot.png" alt="The new dashboard"/>`,
+				`Share this:`,
+			},
+			notWant: []string{"pixel.wp.com", "stats.wordpress.com", "onclick", "srcset", "class=", "target=", "rel=", "width=", "height="},
+		},
+		{
+			fixture: "feedburner.html",
+			baseURL: "http://feeds.feedburner.com/example",
+			want: []string{
+				`<a href="http://feedproxy.google.com/~r/example/~3/abc/">Something about Go</a>`,
+				`<a href="https://example.org/rust">Something about Rust</a>`,
+			},
+			notWant: []string{"<img", "~ff/example?d=", "~r/example/~4"},
+		},
+		{
+			fixture: "hostile.html",
+			baseURL: "https://example.com/post",
+			want: []string{
+				`<p>Hello <b>there</b>.</p>`,
+				`Click me`,
+				`data link`,
+				`<a href="mailto:editor@example.com">Email the editor</a>`,
+				`Terminal trick: ]52;c;ZXZpbA== done`,
+			},
+			notWant: []string{
+				"<script", "evil.example", "document.cookie", "<iframe", "youtube", "javascript:", "JaVaScRiPt",
+				"data:text", "vbscript", "onmouseover", "onerror", "onload", "style", "display: none", "<form",
+				"<input", "password", "<button", "<object", "<embed", "movie.swf", "<svg", "<circle", "<!--",
+				"\x1b", "\x07",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := HTML(string(input), tt.baseURL)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output is missing %q\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("output contains %q\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLRelativeURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		baseURL string
+		want    string
+	}{
+		{"relative link", `<a href="../about">About</a>`, "https://example.com/blog/post/", `<a href="https://example.com/blog/about">About</a>`},
+		{"root relative image", `<img src="/a.png" alt="a">`, "https://example.com/blog/post", `<img src="https://example.com/a.png" alt="a"/>`},
+		{"protocol relative image", `<img src="//cdn.example.com/a.png">`, "https://example.com/", `<img src="https://cdn.example.com/a.png"/>`},
+		{"no base keeps the text only", `<a href="/about">About</a>`, "", `About`},
+		{"no base drops relative images", `<p>x<img src="a.png"></p>`, "not a url", `<p>x</p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HTML(tt.input, tt.baseURL)
+			if got != tt.want {
+				t.Errorf("HTML(%q, %q) = %q, want %q", tt.input, tt.baseURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLTrackingPixels(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		kept  bool
+	}{
+		{"1x1 pixel", `<img src="https://example.com/t.gif" width="1" height="1">`, false},
+		{"0px pixel", `<img src="https://example.com/t.gif" width="0px">`, false},
+		{"feedburner", `<img src="http://feeds.feedburner.com/~r/blog/~4/xyz">`, false},
+		{"doubleclick", `<img src="https://ad.doubleclick.net/ddm/ad.gif">`, false},
+		{"substack open tracking", `<img src="https://eotrx.substackcdn.com/open?token=abc">`, false},
+		{"ordinary image", `<img src="https://example.com/photo.jpg" width="640" height="480">`, true},
+		{"image with no src", `<img alt="nothing">`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HTML(tt.input, "https://example.com/")
+			if kept := strings.Contains(got, "<img"); kept != tt.kept {
+				t.Errorf("HTML(%q) = %q, want image kept: %v", tt.input, got, tt.kept)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain title", "plain title"},
+		{"Hi \x1b]52;c;ZXZpbA==\x07 there", "Hi ]52;c;ZXZpbA== there"},
+		{"\x1b[31mred\x1b[0m", "[31mred[0m"},
+		{"c1 \u009b31m csi", "c1 31m csi"},
+		{"keeps\nnewlines\tand tabs", "keeps\nnewlines\tand tabs"},
+		{"drops\rcarriage returns\x00 and nuls\x7f", "dropscarriage returns and nuls"},
+		{"unicode stays: café – ✓", "unicode stays: café – ✓"},
+	}
+
+	for _, tt := range tests {
+		if got := Text(tt.input); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
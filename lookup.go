@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Number of characters of a uuid shown as the short id of feeds, and the least shown for posts
+const shortIDLength = 8
+
+// A feed or post reference that matched nothing
+type lookupError struct {
+	message string
+}
+
+func (e *lookupError) Error() string {
+	return e.message
+}
+
+func lookupErrorf(format string, args ...any) error {
+	return &lookupError{message: fmt.Sprintf(format, args...)}
+}
+
+// A feed or post reference that matched more than one thing, listing them so the user can
+// pick one
+type ambiguousError struct {
+	ref     string
+	matches []string
+}
+
+func (e *ambiguousError) Error() string {
+	return fmt.Sprintf("%s is ambiguous, it matches:\n%s", e.ref, strings.Join(e.matches, "\n"))
+}
+
+// Finds a feed by its url, its name, or its short id. A url match wins over a name match,
+// which wins over an id match, and more than one match at the same level is an error.
+func lookupFeed(s *state, ref string) (database.Feed, error) {
+	found, err := s.db.URLLookup(context.Background(), ref)
+	if err == nil {
+		return s.db.GetFeedByID(context.Background(), found.ID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, err
+	}
+
+	arg := database.GetFeedsByNameOrIDPrefixParams{Name: ref}
+	if isIDPrefix(ref) {
+		arg.IDPrefix = strings.ToLower(ref)
+	}
+	matches, err := s.db.GetFeedsByNameOrIDPrefix(context.Background(), arg)
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	var byName, byID []database.Feed
+	for _, feed := range matches {
+		if strings.EqualFold(feed.Name, ref) {
+			byName = append(byName, feed)
+		} else {
+			byID = append(byID, feed)
+		}
+	}
+
+	for _, candidates := range [][]database.Feed{byName, byID} {
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return candidates[0], nil
+		default:
+			var lines []string
+			for _, feed := range candidates {
+				lines = append(lines, fmt.Sprintf("  %s  %s  %s", shortID(feed.ID), feed.Name, feed.Url))
+			}
+			return database.Feed{}, &ambiguousError{ref: ref, matches: lines}
+		}
+	}
+
+	return database.Feed{}, lookupErrorf("no feed with url, name or id %s", ref)
+}
+
+// Finds one of the user's posts by the short id shown in browse, a full id, or its number in the
+// unfiltered browse list
+func lookupPost(s *state, user database.User, ref string) (database.GetPostsByIDPrefixRow, error) {
+	if index, err := strconv.Atoi(ref); err == nil && len(ref) < shortIDLength {
+		if index < 1 {
+			return database.GetPostsByIDPrefixRow{}, lookupErrorf("no post number %d, posts are numbered from 1", index)
+		}
+		posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+			UserID:   user.ID,
+			MaxPosts: int32(index),
+		})
+		if err != nil {
+			return database.GetPostsByIDPrefixRow{}, err
+		}
+		if index > len(posts) {
+			return database.GetPostsByIDPrefixRow{}, lookupErrorf("no post number %d, you have %d posts", index, len(posts))
+		}
+		post := posts[index-1]
+		return database.GetPostsByIDPrefixRow{ID: post.ID, Title: post.Title, Url: post.Url, PublishedAt: post.PublishedAt}, nil
+	}
+
+	if !isIDPrefix(ref) {
+		return database.GetPostsByIDPrefixRow{}, lookupErrorf("no post with id %s", ref)
+	}
+	matches, err := s.db.GetPostsByIDPrefix(context.Background(), database.GetPostsByIDPrefixParams{
+		UserID: user.ID,
+		Prefix: strings.ToLower(ref),
+	})
+	if err != nil {
+		return database.GetPostsByIDPrefixRow{}, err
+	}
+
+	switch len(matches) {
+	case 0:
+		return database.GetPostsByIDPrefixRow{}, lookupErrorf("no post with id %s", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		var candidates []string
+		for _, match := range matches {
+			candidates = append(candidates, fmt.Sprintf("  %s  %s", match.ID, match.Title))
+		}
+		return database.GetPostsByIDPrefixRow{}, &ambiguousError{ref: "post id " + ref, matches: candidates}
+	}
+}
+
+// Returns the start of a feed's id, enough to tell feeds apart in practice
+func shortID(id uuid.UUID) string {
+	return id.String()[:shortIDLength]
+}
+
+// Returns the shortest start of a post's id that no other post the user follows shares, and
+// never less than shortIDLength. There can be far more posts than feeds, so a fixed length would
+// sooner or later print ids that lookupPost finds ambiguous.
+func shortPostID(s *state, user database.User, id uuid.UUID) (string, error) {
+	neighbours, err := s.db.GetPostIDNeighbours(context.Background(), database.GetPostIDNeighboursParams{
+		UserID: user.ID,
+		ID:     id,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	full := id.String()
+	length := shortIDLength
+	for _, other := range []uuid.NullUUID{neighbours.PreviousID, neighbours.NextID} {
+		if !other.Valid {
+			continue
+		}
+		shared := 0
+		for shared < len(full) && full[shared] == other.UUID.String()[shared] {
+			shared++
+		}
+		length = max(length, shared+1)
+	}
+	return full[:min(length, len(full))], nil
+}
+
+// Whether ref could be the start of a uuid, so it can go into a LIKE pattern as it is
+func isIDPrefix(ref string) bool {
+	return ref != "" && strings.Trim(strings.ToLower(ref), "0123456789abcdef-") == ""
+}
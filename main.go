@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
 	"html"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -16,6 +21,9 @@ import (
 
 	"github.com/Daxin319/Gator/internal/config"
 	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/readability"
+	"github.com/Daxin319/Gator/internal/render"
+	"github.com/Daxin319/Gator/internal/sanitize"
 	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
 	"github.com/google/uuid"
 
@@ -26,33 +34,9 @@ func main() {
 	// Read config file
 	configFile := config.Read()
 
-	// Ensure postgres is running
-	database.EnsurePostgresRunning()
-
-	// Check for db and create if none
-	database.EnsureDatabaseExists()
-
-	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=gator sslmode=disable"
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		fmt.Println("ERROR: Cannot connect to PostgreSQL:", err)
-	}
-	defer db.Close()
-
-	var connectedDB string
-	err = db.QueryRow("SELECT current_database();").Scan(&connectedDB)
-	if err != nil {
-		log.Fatal("ERROR: Could not fetch connected database:", err)
-	}
-
-	fmt.Printf("GatorDB is ready!\n\n")
-	fmt.Printf("------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------\n\n\n")
-
-	dbQueries := database.New(db)
-
 	currentState := state{
-		dbQueries,
-		&configFile,
+		config: &configFile,
+		out:    os.Stdout,
 	}
 
 	commands := commands{
@@ -61,7 +45,7 @@ func main() {
 
 	commands.register("login", handlerLogins)
 	commands.register("register", handlerRegister)
-	commands.register("reset", handlerReset)
+	commands.register("reset", middlewareLoggedIn(handlerReset))
 	commands.register("users", handlerList)
 	commands.register("agg", handlerAgg)
 	commands.register("addfeed", middlewareLoggedIn(handlerAddFeed))
@@ -70,6 +54,17 @@ func main() {
 	commands.register("following", middlewareLoggedIn(handlerFollowing))
 	commands.register("unfollow", middlewareLoggedIn(handlerUnfollow))
 	commands.register("browse", middlewareLoggedIn(handlerBrowse))
+	commands.register("tui", middlewareLoggedIn(handlerTUI))
+	commands.register("open", middlewareLoggedIn(handlerOpen))
+	commands.register("folder", middlewareLoggedIn(handlerFolder))
+	commands.register("rename", middlewareLoggedIn(handlerRename))
+	commands.register("export", middlewareLoggedIn(handlerExport))
+	commands.register("import", middlewareLoggedIn(handlerImport))
+	commands.register("feed", middlewareLoggedIn(handlerFeed))
+	commands.register("admin", middlewareLoggedIn(handlerAdmin))
+	commands.register("db", handlerDB)
+	commands.register("token", middlewareLoggedIn(handlerToken))
+	commands.register("serve", handlerServe)
 
 	args := os.Args
 
@@ -84,12 +79,57 @@ func main() {
 		args[2:],
 	}
 
-	commands.run(&currentState, command)
+	// Bootstrapping has to work before there's a database to connect to,
+	// db migrate decides for itself which migrations to run, and db bench uses a database of its own
+	if sub := dbSubcommand(command); sub != "bootstrap" && sub != "bench" {
+		db, err := database.Connect(configFile.DatabaseURL())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		if sub != "migrate" {
+			err = database.Migrate(db)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		// On stderr, so output like export's OPML can be redirected to a file as it is
+		fmt.Fprintf(os.Stderr, "GatorDB is ready!\n\n")
+		fmt.Fprintf(os.Stderr, "------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------\n\n\n")
+
+		currentState.db = database.NewStore(db)
+		currentState.sqlDB = db
+	}
+
+	err := commands.run(&currentState, command)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
 
+const maxArticleSize = 5 << 20
+
 type state struct {
-	db     *database.Queries
+	db     database.Store
+	sqlDB  *sql.DB
 	config *config.Config
+	// Where commands print their output
+	out io.Writer
+}
+
+// Runs fn as one unit of work. Everything fn does through the state it's given is saved
+// together, or not at all if fn returns an error.
+func (s *state) inTx(fn func(s *state) error) error {
+	return s.db.InTx(context.Background(), func(tx database.Store) error {
+		txState := *s
+		txState.db = tx
+		return fn(&txState)
+	})
 }
 
 type command struct {
@@ -105,13 +145,27 @@ func (c *commands) register(name string, f func(*state, command) error) {
 	c.validCommands[name] = f
 }
 
+// Parses flags from args, allowing them to appear before or after positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
 func (c *commands) run(s *state, cmd command) error {
-	err := c.validCommands[cmd.name](s, cmd)
-	if err != nil {
-		return fmt.Errorf("unknown command")
+	handler, ok := c.validCommands[cmd.name]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", cmd.name)
 	}
 
-	return nil
+	return handler(s, cmd)
 }
 
 type RSSFeed struct {
@@ -121,36 +175,89 @@ type RSSFeed struct {
 		Description string    `xml:"description"`
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
+
+	// Set when the feed answered with permanent redirects to a new url
+	MovedTo string `xml:"-"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	Content     string        `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string        `xml:"pubDate"`
+	Authors     []RSSAuthor   `xml:"author"`
+	Creators    []string      `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []RSSCategory `xml:"category"`
+}
+
+// RSS puts the author straight into <author>, Atom nests it as <author><name>
+type RSSAuthor struct {
+	Text string `xml:",chardata"`
+	Name string `xml:"name"`
+}
+
+// RSS puts the category in the element body, Atom uses the term attribute
+type RSSCategory struct {
+	Text string `xml:",chardata"`
+	Term string `xml:"term,attr"`
+}
+
+// Returns the de-duplicated author names of an item from <author> and dc:creator
+func (item RSSItem) AuthorNames() []string {
+	var names []string
+	for _, author := range item.Authors {
+		names = append(names, author.Name, author.Text)
+	}
+	names = append(names, item.Creators...)
+	return uniqueNonEmpty(names)
+}
+
+// Returns the de-duplicated category names of an item
+func (item RSSItem) CategoryNames() []string {
+	var names []string
+	for _, category := range item.Categories {
+		names = append(names, category.Term, category.Text)
+	}
+	return uniqueNonEmpty(names)
+}
+
+func uniqueNonEmpty(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, value := range values {
+		value = strings.TrimSpace(sanitize.Text(html.UnescapeString(value)))
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		result = append(result, value)
+	}
+	return result
 }
 
 func handlerLogins(s *state, cmd command) error {
 	if len(cmd.arguments) == 0 {
-		err := fmt.Errorf("only one username expected")
-		fmt.Println(err)
-		os.Exit(1)
+		return fmt.Errorf("only one username expected")
 	}
 
 	if _, err := s.db.GetUser(context.Background(), cmd.arguments[0]); err != nil {
-		fmt.Println("user does not exist")
-		os.Exit(1)
+		return fmt.Errorf("user does not exist")
 	}
 	s.config.SetUser(cmd.arguments[0])
-	fmt.Printf("Username set to %s\n", cmd.arguments[0])
+	fmt.Fprintf(s.out, "Username set to %s\n", cmd.arguments[0])
 	return nil
 }
 
 func handlerRegister(s *state, cmd command) error {
 	if len(cmd.arguments) == 0 {
-		err := fmt.Errorf("expecting an argument")
-		fmt.Println(err)
-		return err
+		return fmt.Errorf("expecting an argument")
+	}
+
+	// The first user to register becomes the admin
+	existing, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return fmt.Errorf("error getting users from database: %w", err)
 	}
 
 	// Prepare the user creation parameters
@@ -159,43 +266,32 @@ func handlerRegister(s *state, cmd command) error {
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Name:      cmd.arguments[0],
+		IsAdmin:   len(existing) == 0,
 	}
 
 	// Insert user into the database
-	_, err := s.db.CreateUser(context.Background(), args)
+	_, err = s.db.CreateUser(context.Background(), args)
 	if err != nil {
-		fmt.Println("ERROR: Failed to insert user into database:", err)
-		return err
+		return fmt.Errorf("error creating user: %w", err)
 	}
 
 	// Set the username in the config
 	s.config.SetUser(cmd.arguments[0])
 
-	fmt.Printf("Username set to %s\n", cmd.arguments[0])
-	return nil
-}
-
-func handlerReset(s *state, cmd command) error {
-	err := s.db.ResetUsers(context.Background())
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	fmt.Println("database reset!")
+	fmt.Fprintf(s.out, "Username set to %s\n", cmd.arguments[0])
 	return nil
 }
 
 func handlerList(s *state, cmd command) error {
 	users, err := s.db.GetUsers(context.Background())
 	if err != nil {
-		fmt.Println("error getting users from database", err)
-		os.Exit(1)
+		return fmt.Errorf("error getting users from database: %w", err)
 	}
 	for _, user := range users {
 		if user == s.config.CurrentUserName {
-			fmt.Printf("* %s (current)\n", user)
+			fmt.Fprintf(s.out, "* %s (current)\n", user)
 		} else {
-			fmt.Printf("* %s\n", user)
+			fmt.Fprintf(s.out, "* %s\n", user)
 		}
 	}
 	return nil
@@ -204,16 +300,15 @@ func handlerList(s *state, cmd command) error {
 func handlerListFeeds(s *state, cmd command) error {
 	feeds, err := s.db.GetFeeds(context.Background())
 	if err != nil {
-		fmt.Println("error getting feeds from database")
-		os.Exit(1)
+		return fmt.Errorf("error getting feeds from database")
 	}
 
 	for _, feed := range feeds {
 		creator, err := s.db.GetCreator(context.Background(), feed.UserID)
 		if err != nil {
-			fmt.Println("error retrieving creator data")
+			fmt.Fprintln(s.out, "error retrieving creator data")
 		}
-		fmt.Printf("- Feed: %s\n  URL: %s\n  Created by: %s\n\n", feed.Name, feed.Url, creator)
+		fmt.Fprintf(s.out, "- Feed: %s\n  ID: %s\n  URL: %s\n  Created by: %s\n\n", feed.Name, shortID(feed.ID), feed.Url, creator)
 	}
 
 	return nil
@@ -221,260 +316,593 @@ func handlerListFeeds(s *state, cmd command) error {
 
 func handlerAgg(s *state, cmd command) error {
 	if len(cmd.arguments) == 0 {
-		fmt.Println("expecting one argument (time between requests: '1m', '8h', '30s' etc.)")
-		os.Exit(1)
+		return fmt.Errorf("expecting one argument (time between requests: '1m', '8h', '30s' etc.)")
 	}
 	timeBetweenRequests, err := time.ParseDuration(cmd.arguments[0])
 	if err != nil {
-		fmt.Println("invalid time format")
-		os.Exit(1)
+		return fmt.Errorf("invalid time format")
 	}
 	if timeBetweenRequests < (time.Duration(5) * time.Second) {
-		fmt.Println("too short of a time period. Don't DOS people.")
-		os.Exit(1)
+		return fmt.Errorf("too short of a time period. Don't DOS people.")
 	}
 
+	var lastPruned time.Time
 	ticker := time.NewTicker(timeBetweenRequests)
 	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+		// A feed failing to fetch shouldn't stop the others
+		if err := scrapeFeeds(s); err != nil {
+			log.Println(err)
+		}
+
+		if time.Since(lastPruned) >= pruneInterval {
+			prunePostsForAgg(s)
+			lastPruned = time.Now()
+		}
 	}
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) < 2 {
-		fmt.Println("not enough arguments provided, please provide a name and url")
-		os.Exit(1)
+		return fmt.Errorf("not enough arguments provided, please provide a name and url")
 	}
 
+	feed, err := addFeed(s, user, cmd.arguments[0], cmd.arguments[1])
+	if err != nil {
+		return err
+	}
+
+	feed_name := feed.Name
+
+	fmt.Fprintf(s.out, "%s has followed %s\n", user.Name, feed_name)
+
+	fmt.Fprintln(s.out, feed)
+	return nil
+}
+
+// Adds a feed and has user follow it
+func addFeed(s *state, user database.User, name, feedURL string) (database.Feed, error) {
 	user_id := user.ID
 
+	err := validateFeed(name, feedURL)
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	// The url may belong to a feed that's already here, possibly under an address it has since moved from
+	existing, err := s.db.URLLookup(context.Background(), feedURL)
+	if err == nil {
+		return database.Feed{}, &feedExistsError{url: feedURL, name: existing.Name, id: existing.ID}
+	}
+
 	args := database.CreateFeedParams{
 		ID:            uuid.New(),
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
-		Name:          cmd.arguments[0],
-		Url:           cmd.arguments[1],
+		Name:          name,
+		Url:           feedURL,
 		UserID:        user_id,
 		LastFetchedAt: time.Now(),
 	}
 
-	feed, err := s.db.CreateFeed(context.Background(), args)
-	if err != nil {
-		fmt.Printf("error creating feed")
-		os.Exit(1)
-	}
+	// A feed nobody follows would only be fetched for nothing, so it goes in along with the follow or not at all
+	var feed database.Feed
+	err = s.inTx(func(s *state) error {
+		feed, err = s.db.CreateFeed(context.Background(), args)
+		if err != nil {
+			return fmt.Errorf("error creating feed: %w", err)
+		}
+		return followFeed(s, user, feed.ID)
+	})
+	return feed, err
+}
 
-	feed_id := feed.ID
-	feed_name := feed.Name
+// Returned by addFeed when the name or url can't be used for a feed
+type invalidFeedError struct {
+	message string
+}
 
-	follow_args := database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    user_id,
-		FeedID:    feed_id,
-	}
+func (e *invalidFeedError) Error() string {
+	return e.message
+}
 
-	_, err = s.db.CreateFeedFollow(context.Background(), follow_args)
-	if err != nil {
-		fmt.Println("error creating feed_follow record")
-		os.Exit(1)
+// The checks a new feed has to pass, wherever it's added from
+func validateFeed(name, feedURL string) error {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(feedURL) == "" {
+		return &invalidFeedError{message: "a feed needs a name and a url"}
+	}
+	u, err := url.Parse(feedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &invalidFeedError{message: fmt.Sprintf("%s isn't an http or https url", feedURL)}
 	}
+	return nil
+}
 
-	fmt.Printf("%s has followed %s\n", user.Name, feed_name)
+// Returned by addFeed when the url is already in gator
+type feedExistsError struct {
+	url  string
+	name string
+	id   uuid.UUID
+}
 
-	fmt.Println(feed)
-	return nil
+func (e *feedExistsError) Error() string {
+	return fmt.Sprintf("%s is already in gator as %s, follow it with: gator follow %s", e.url, e.name, shortID(e.id))
 }
 
 func handlerFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) == 0 {
-		fmt.Printf("expecting 1 argument (url)")
-		os.Exit(1)
+		return fmt.Errorf("expecting 1 argument (feed id, name or url)")
 	}
 
-	user_id := user.ID
+	feed, err := lookupFeed(s, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
 
-	feed, err := s.db.URLLookup(context.Background(), cmd.arguments[0])
+	err = followFeed(s, user, feed.ID)
 	if err != nil {
-		fmt.Println("error getting feed id")
-		os.Exit(1)
+		return err
 	}
 
-	feed_id := feed.ID
-	feed_name := feed.Name
+	fmt.Fprintf(s.out, "%s has followed %s\n", user.Name, feed.Name)
+
+	return nil
+}
 
+func followFeed(s *state, user database.User, feedID uuid.UUID) error {
 	args := database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		UserID:    user_id,
-		FeedID:    feed_id,
+		UserID:    user.ID,
+		FeedID:    feedID,
 	}
 
-	_, err = s.db.CreateFeedFollow(context.Background(), args)
+	_, err := s.db.CreateFeedFollow(context.Background(), args)
 	if err != nil {
-		fmt.Println("error creating feed_follow record")
-		os.Exit(1)
+		return fmt.Errorf("error creating feed_follow record: %w", err)
 	}
-
-	fmt.Printf("%s has followed %s\n", user.Name, feed_name)
-
 	return nil
 }
 
 func handlerFollowing(s *state, cmd command, user database.User) error {
 	following, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
 	if err != nil {
-		fmt.Println("error getting followed feeds")
+		return fmt.Errorf("error getting followed feeds: %w", err)
+	}
+
+	moves, err := s.db.GetFeedMovesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting feed moves: %w", err)
+	}
+
+	// Tell the user once about feeds that moved or went away since they last looked
+	var notices []string
+	for _, move := range moves {
+		notices = append(notices, fmt.Sprintf("%s moved from %s to %s", move.FeedName, move.OldUrl, move.NewUrl))
+	}
+	for _, feed := range following {
+		if !feed.FeedActive && feed.FeedDeactivatedAt.Valid && feed.FeedDeactivatedAt.Time.After(feed.NotifiedAt) {
+			notices = append(notices, fmt.Sprintf("%s is gone and won't be fetched anymore, unfollow it or give it a new url with: gator feed edit %s --url", feed.FeedName, shortID(feed.FeedID)))
+		}
 	}
+	if len(notices) > 0 {
+		for _, notice := range notices {
+			fmt.Fprintf(s.out, "! %s\n", notice)
+		}
+		fmt.Fprintln(s.out)
 
-	fmt.Printf("%s is following:\n\n", user.Name)
+		err = s.db.MarkFollowsNotified(context.Background(), database.MarkFollowsNotifiedParams{
+			UserID:     user.ID,
+			NotifiedAt: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("error updating feed_follow records: %w", err)
+		}
+	}
+
+	fmt.Fprintf(s.out, "%s is following:\n\n", user.Name)
 
+	// Follows come sorted by folder with unfiled feeds last, only show headings once folders are in use
+	useFolders := false
 	for _, feed := range following {
-		fmt.Printf("  -%s\n", feed.FeedName)
+		useFolders = useFolders || feed.FolderName != ""
+	}
+
+	folder := ""
+	for i, feed := range following {
+		if useFolders && (i == 0 || feed.FolderName != folder) {
+			folder = feed.FolderName
+			heading := folder
+			if heading == "" {
+				heading = "Unfiled"
+			}
+			fmt.Fprintf(s.out, "%s\n", heading)
+		}
+		gone := ""
+		if !feed.FeedActive {
+			gone = " (gone)"
+		}
+		fmt.Fprintf(s.out, "  -%s  %s%s\n", shortID(feed.FeedID), feed.FeedName, gone)
 	}
 
 	return nil
 }
 
+func handlerRename(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("expecting 1 or more arguments (feed id, name or url, new title)")
+	}
+
+	feed, err := lookupFeed(s, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+
+	// The title is only stored on this user's follow, the feed's own name stays the same for everyone
+	title := strings.TrimSpace(strings.Join(cmd.arguments[1:], " "))
+
+	updated, err := s.db.SetFollowTitle(context.Background(), database.SetFollowTitleParams{
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		Title:     title,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating feed_follow record: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s isn't following %s", user.Name, feed.Name)
+	}
+
+	if title == "" {
+		fmt.Fprintf(s.out, "%s is called %s again\n", feed.Name, feed.Name)
+	} else {
+		fmt.Fprintf(s.out, "%s will be shown as %s\n", feed.Name, title)
+	}
+	return nil
+}
+
 func handlerUnfollow(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) == 0 {
-		fmt.Println("expecting 1 argument (url)")
-		os.Exit(1)
+		return fmt.Errorf("expecting 1 argument (feed id, name or url)")
 	}
 
-	feed, _ := s.db.URLLookup(context.Background(), cmd.arguments[0])
+	feed, err := lookupFeed(s, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
 
 	arg := database.DeleteFollowParams{
 		Name: user.Name,
-		Url:  cmd.arguments[0],
+		Url:  feed.Url,
 	}
 
-	s.db.DeleteFollow(context.Background(), arg)
-	fmt.Printf("You have unfollowed %s\n", feed.Name)
+	deleted, err := s.db.DeleteFollow(context.Background(), arg)
+	if err != nil {
+		return fmt.Errorf("error deleting feed_follow record: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%s isn't following %s", user.Name, feed.Name)
+	}
+	fmt.Fprintf(s.out, "You have unfollowed %s\n", feed.Name)
 
 	return nil
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
-	limit := 2
-	if len(cmd.arguments) == 1 {
-		userLimit, _ := strconv.Atoi(cmd.arguments[0])
-		limit = userLimit - 1
+	fs := flag.NewFlagSet("browse", flag.ExitOnError)
+	folder := fs.String("folder", "", "only show posts from feeds in this folder")
+	author := fs.String("author", "", "only show posts by this author")
+	category := fs.String("category", "", "only show posts in this category")
+	full := fs.Bool("full", false, "show the full article instead of the summary when available")
+	noPager := fs.Bool("no-pager", false, "print straight to stdout instead of through the pager")
+	before := fs.String("before", "", "only show posts older than this one, to page through them")
+	arguments := parseFlags(fs, cmd.arguments)
+
+	limit := 3
+	if len(arguments) == 1 {
+		userLimit, err := strconv.Atoi(arguments[0])
+		if err != nil || userLimit < 1 {
+			return fmt.Errorf("the limit must be a number of posts, e.g. gator browse 10")
+		}
+		limit = userLimit
 	}
 
-	posts, err := s.db.GetPostsForUser(context.Background(), user.ID)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	arg := database.GetPostsForUserParams{
+		UserID:   user.ID,
+		Folder:   *folder,
+		Author:   *author,
+		Category: *category,
+		MaxPosts: int32(min(limit, math.MaxInt32)),
 	}
 
-	if limit > len(posts) {
-		limit = len(posts)
+	// Pages carry on from a post rather than skipping a count, so the database can go
+	// straight to them through the index however deep they are
+	if *before != "" {
+		post, err := lookupPost(s, user, *before)
+		if err != nil {
+			return err
+		}
+		arg.BeforePublishedAt = sql.NullString{String: post.PublishedAt, Valid: true}
+		arg.BeforeID = uuid.NullUUID{UUID: post.ID, Valid: true}
+	}
+
+	posts, err := s.db.GetPostsForUser(context.Background(), arg)
+	if err != nil {
+		return err
 	}
 
-	for i, post := range posts {
-		if i > limit {
+	r := render.For(s.out)
+	out := startPager(s, *noPager)
+	defer out.Close()
+
+	var id string
+	for _, post := range posts {
+		if out.Closed() {
 			break
 		}
-		fmt.Printf("\n- %s\n", post.Title)
-		fmt.Printf(" - %s          %s\n\n", post.FeedTitle, post.PublishedAt)
-		fmt.Printf(" %s\n", post.Description)
-		fmt.Printf(" <Ctrl + LMB> to visit full article in browser -> %s\n\n\n", post.Url)
-		fmt.Printf("------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------\n\n")
+		id, err = shortPostID(s, user, post.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "\n%s", r.Heading(post.Title))
+		fmt.Fprintf(out, "%s\n", r.Faint(id+"    "+post.FeedTitle+"    "+post.PublishedAt))
+		if post.Authors != "" {
+			fmt.Fprintf(out, "%s\n", r.Faint("by "+strings.Join(post.AuthorNames(), ", ")))
+		}
+		if post.Categories != "" {
+			fmt.Fprintf(out, "%s\n", r.Faint("tags: "+strings.Join(post.CategoryNames(), ", ")))
+		}
+		// Feeds that only publish content:encoded have no description to show as the summary
+		body := post.Description
+		if (*full || body == "") && post.Content != "" {
+			body = post.Content
+		}
+		fmt.Fprintf(out, "\n%s\n", r.Markdown(body))
+		fmt.Fprintf(out, "Read more: %s  %s\n\n", r.Link(post.Url, post.Url), r.Faint("(gator open "+id+")"))
+		fmt.Fprint(out, r.Rule())
+	}
+
+	if len(posts) == limit && !out.Closed() {
+		fmt.Fprintf(out, "%s\n", r.Faint("More: gator browse --before "+id))
 	}
 
 	return nil
 }
 
+func handlerOpen(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("open", flag.ExitOnError)
+	copyURL := fs.Bool("copy", false, "copy the post url to the clipboard instead of opening it")
+	arguments := parseFlags(fs, cmd.arguments)
+
+	if len(arguments) != 1 {
+		return fmt.Errorf("expecting 1 argument (post id or number from browse)")
+	}
+
+	post, err := lookupPost(s, user, arguments[0])
+	if err != nil {
+		return err
+	}
+
+	if *copyURL {
+		copyToClipboard(s.out, post.Url)
+		fmt.Fprintf(s.out, "Copied %s to the clipboard\n", sanitize.Text(post.Url))
+		return nil
+	}
+
+	err = openInBrowser(post.Url)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SetPostRead(context.Background(), database.SetPostReadParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		Read:      true,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		fmt.Fprintln(s.out, "error marking post read:", err)
+	}
+
+	fmt.Fprintf(s.out, "Opened %s\n", sanitize.Text(post.Title))
+	return nil
+}
+
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
 		user, err := s.db.GetUser(context.Background(), s.config.CurrentUserName)
 		if err != nil {
-			fmt.Println("error getting user id")
-			os.Exit(1)
+			return fmt.Errorf("error getting user %s, register or log in first: %w", s.config.CurrentUserName, err)
 		}
-		handler(s, cmd, user)
-		return nil
+		return handler(s, cmd, user)
 	}
 }
 
+// Returned by fetchFeed when the server says the feed has been removed for good
+var errFeedGone = errors.New("feed is gone")
+
 func fetchFeed(c context.Context, feedURL string) (*RSSFeed, error) {
-	client := http.Client{}
+	// The feed has only moved if every redirect on the way was a permanent one
+	redirected, permanent := false, true
+	client := http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			redirected = true
+			if code := req.Response.StatusCode; code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+				permanent = false
+			}
+			return nil
+		},
+	}
 
 	req, err := http.NewRequestWithContext(c, "GET", feedURL, nil)
 	if err != nil {
-		fmt.Println("error making request")
-		os.Exit(1)
+		return nil, fmt.Errorf("error making request: %w", err)
 	}
 
 	req.Header.Set("User-Agent", "gator")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Println("error performing request")
-		os.Exit(1)
+		return nil, fmt.Errorf("error performing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return nil, errFeedGone
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Println("error reading xml body")
-		os.Exit(1)
+		return nil, fmt.Errorf("error reading xml body: %w", err)
 	}
 
-	feedStruct := RSSFeed{}
-
-	err = xml.Unmarshal(body, &feedStruct)
+	feedStruct, err := parseFeed(body)
 	if err != nil {
-		fmt.Println("error unmarshalling xml")
+		return nil, fmt.Errorf("error unmarshalling xml: %w", err)
 	}
 
-	feedStruct.Channel.Title = html.UnescapeString(feedStruct.Channel.Title)
-	feedStruct.Channel.Description = html.UnescapeString(feedStruct.Channel.Description)
+	feedStruct.Channel.Title = sanitize.Text(html.UnescapeString(feedStruct.Channel.Title))
+	feedStruct.Channel.Description = sanitize.Text(html.UnescapeString(feedStruct.Channel.Description))
+
+	// Descriptions are html already, unescaping them again would turn escaped text into markup.
+	// They go through the sanitizer in postMarkdown instead.
+	for i := range feedStruct.Channel.Item {
+		// Unescaping can turn &#27; into a real escape character, so control characters go after it
+		feedStruct.Channel.Item[i].Title = sanitize.Text(html.UnescapeString(feedStruct.Channel.Item[i].Title))
+	}
 
-	for _, item := range feedStruct.Channel.Item {
-		item.Title = html.UnescapeString(item.Title)
-		item.Description = html.UnescapeString(item.Description)
+	if redirected && permanent {
+		feedStruct.MovedTo = resp.Request.URL.String()
 	}
 
 	return &feedStruct, nil
 }
 
-func scrapeFeeds(s *state) {
+// Parses an RSS or an Atom feed, telling them apart by the root element
+func parseFeed(body []byte) (RSSFeed, error) {
+	root := ""
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for root == "" {
+		token, err := decoder.Token()
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = start.Name.Local
+		}
+	}
+
+	if root == "feed" {
+		var atom atomFeed
+		err := xml.Unmarshal(body, &atom)
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		return atom.toRSS(), nil
+	}
+
+	var feed RSSFeed
+	err := xml.Unmarshal(body, &feed)
+	return feed, err
+}
+
+func scrapeFeeds(s *state) error {
 	nextFeed, err := s.db.GetNextFeedToFetch(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no feeds to fetch, add one with: gator addfeed <name> <url>")
+	}
 	if err != nil {
-		fmt.Println("error getting next feed to fetch")
-		os.Exit(1)
+		return fmt.Errorf("error getting next feed to fetch: %w", err)
 	}
 
+	err = scrapeFeed(s, nextFeed)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", nextFeed.Name, err)
+	}
+	return nil
+}
+
+// Fetches a single feed and saves any posts that aren't in the database yet.
+// Problems with individual items are logged and the item is skipped.
+func scrapeFeed(s *state, nextFeed database.GetNextFeedToFetchRow) error {
 	arg := database.MarkFeedFetchedParams{
 		LastFetchedAt: time.Now(),
 		ID:            nextFeed.ID,
 	}
 
-	err = s.db.MarkFeedFetched(context.Background(), arg)
+	err := s.db.MarkFeedFetched(context.Background(), arg)
 	if err != nil {
-		fmt.Println("error marking feed fetched")
-		os.Exit(1)
+		return fmt.Errorf("error marking feed fetched: %w", err)
 	}
 
 	feed, err := fetchFeed(context.Background(), nextFeed.Url)
+	if errors.Is(err, errFeedGone) {
+		// Followers hear about it the next time they run following
+		err = s.db.SetFeedActive(context.Background(), database.SetFeedActiveParams{
+			ID:            nextFeed.ID,
+			Active:        false,
+			DeactivatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			UpdatedAt:     time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("error marking feed inactive: %w", err)
+		}
+		log.Printf("%s is gone, it won't be fetched anymore", nextFeed.Name)
+		return nil
+	}
 	if err != nil {
-		fmt.Println("error fetching feed")
+		return err
+	}
+
+	if feed.MovedTo != "" {
+		err = s.inTx(func(s *state) error {
+			return setFeedURL(s, nextFeed.ID, nextFeed.Url, feed.MovedTo)
+		})
+		if err != nil {
+			log.Printf("%s has moved to %s but its url can't be updated: %v", nextFeed.Name, feed.MovedTo, err)
+		} else {
+			log.Printf("%s has moved to %s", nextFeed.Name, feed.MovedTo)
+		}
 	}
 
 	for _, item := range feed.Channel.Item {
 		formattedDate, err := parseTimeToRFC3339(item.PubDate)
 		if err != nil {
-			fmt.Println("ERROR unable to format date: ", err)
-			os.Exit(1)
+			log.Println("ERROR unable to format date: ", err)
+			continue
 		}
 
-		markdown, err := htmltomarkdown.ConvertString(item.Description)
+		markdown, err := postMarkdown(item.Description, item.Link)
 		if err != nil {
-			fmt.Println("error converting description from html to markdown")
-			os.Exit(1)
+			log.Println("error converting description from html to markdown:", err)
+			continue
+		}
+
+		// WordPress and Substack put the whole post in content:encoded and only a teaser in the description
+		content := ""
+		if item.Content != "" {
+			content, err = postMarkdown(item.Content, item.Link)
+			if err != nil {
+				log.Println("error converting content from html to markdown:", err)
+				continue
+			}
+		}
+
+		// Posts removed by retention would otherwise come straight back while they're still in the feed
+		isPruned, err := s.db.IsPostPruned(context.Background(), item.Link)
+		if err != nil {
+			log.Println("error checking for pruned post:", err)
+			continue
+		}
+		if isPruned {
+			continue
 		}
 
 		args := database.CreatePostParams{
@@ -486,18 +914,98 @@ func scrapeFeeds(s *state) {
 			Description: markdown,
 			PublishedAt: formattedDate,
 			FeedID:      nextFeed.ID,
+			Content:     content,
 		}
 
-		_, err = s.db.CreatePost(context.Background(), args)
+		post, err := s.db.CreatePost(context.Background(), args)
 		if err != nil {
-			if dup := strings.Contains(err.Error(), "unique"); dup {
+			if dup := database.IsUniqueViolation(err); dup {
 				continue
 			} else {
-				fmt.Println(err)
+				log.Println(err)
+				continue
+			}
+
+		}
+
+		if nextFeed.FetchFullText && content == "" && item.Link != "" {
+			content, err := fetchArticle(context.Background(), item.Link)
+			if err != nil {
+				log.Printf("error extracting full text from %s: %v", item.Link, err)
+			} else {
+				err = s.db.UpdatePostContent(context.Background(), database.UpdatePostContentParams{
+					ID:        post.ID,
+					Content:   content,
+					UpdatedAt: time.Now(),
+				})
+				if err != nil {
+					log.Println("error saving post content:", err)
+				}
+			}
+		}
+
+		for _, author := range item.AuthorNames() {
+			err = s.db.CreatePostAuthor(context.Background(), database.CreatePostAuthorParams{
+				PostID: post.ID,
+				Name:   author,
+			})
+			if err != nil {
+				log.Println("error saving post author:", err)
 			}
+		}
 
+		for _, category := range item.CategoryNames() {
+			err = s.db.CreatePostCategory(context.Background(), database.CreatePostCategoryParams{
+				PostID: post.ID,
+				Name:   category,
+			})
+			if err != nil {
+				log.Println("error saving post category:", err)
+			}
 		}
 	}
+
+	return nil
+}
+
+// Downloads an article page and returns the main body converted to markdown
+func fetchArticle(c context.Context, articleURL string) (string, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+
+	req, err := http.NewRequestWithContext(c, "GET", articleURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("User-Agent", "gator")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return "", fmt.Errorf("not an html page: %s", contentType)
+	}
+
+	// Cap the download so a huge page can't stall the aggregator
+	articleHTML, err := readability.Extract(io.LimitReader(resp.Body, maxArticleSize))
+	if err != nil {
+		return "", err
+	}
+
+	return postMarkdown(articleHTML, articleURL)
+}
+
+// Sanitizes html from a feed or article page and converts it to markdown.
+// Relative links and images are resolved against the post url.
+func postMarkdown(rawHTML string, postURL string) (string, error) {
+	return htmltomarkdown.ConvertString(sanitize.HTML(rawHTML, postURL))
 }
 
 func parseTimeToRFC3339(input string) (string, error) {
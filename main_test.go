@@ -0,0 +1,797 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/config"
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/database/dbtest"
+	"github.com/google/uuid"
+)
+
+// A state backed by the memory store, with nobody logged in
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	return &state{db: database.NewMemoryStore(), config: &config.Config{}, out: io.Discard}
+}
+
+// Runs a command the way main does, as whoever the config says is logged in, and returns what it printed
+func runTestCommand(s *state, name string, args ...string) (string, error) {
+	handlers := map[string]func(*state, command) error{
+		"register":  handlerRegister,
+		"login":     handlerLogins,
+		"users":     handlerList,
+		"feeds":     handlerListFeeds,
+		"addfeed":   middlewareLoggedIn(handlerAddFeed),
+		"follow":    middlewareLoggedIn(handlerFollow),
+		"following": middlewareLoggedIn(handlerFollowing),
+		"unfollow":  middlewareLoggedIn(handlerUnfollow),
+		"browse":    middlewareLoggedIn(handlerBrowse),
+		"open":      middlewareLoggedIn(handlerOpen),
+		"folder":    middlewareLoggedIn(handlerFolder),
+		"rename":    middlewareLoggedIn(handlerRename),
+		"export":    middlewareLoggedIn(handlerExport),
+		"import":    middlewareLoggedIn(handlerImport),
+		"feed":      middlewareLoggedIn(handlerFeed),
+		"admin":     middlewareLoggedIn(handlerAdmin),
+		"db":        handlerDB,
+		"token":     middlewareLoggedIn(handlerToken),
+		"reset":     middlewareLoggedIn(handlerReset),
+	}
+	var out bytes.Buffer
+	s.out = &out
+	err := handlers[name](s, command{name: name, arguments: args})
+	return out.String(), err
+}
+
+func followedFeeds(t *testing.T, s *state, user database.User) []string {
+	t.Helper()
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, follow := range follows {
+		names = append(names, follow.FeedName)
+	}
+	return names
+}
+
+func TestAddFeed(t *testing.T) {
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	feed, err := addFeed(s, alice, "Blog", "https://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := followedFeeds(t, s, alice); len(got) != 1 || got[0] != "Blog" {
+		t.Errorf("alice follows %q after addfeed, want only Blog", got)
+	}
+	if err := setFeedURL(s, feed.ID, feed.Url, "https://example.com/new-feed"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		feed    string
+		url     string
+		invalid bool
+		exists  bool
+	}{
+		{"no name", " ", "https://example.com/other", true, false},
+		{"no url", "Other", "", true, false},
+		{"javascript url", "Other", "javascript:alert(1)", true, false},
+		{"file url", "Other", "file:///etc/passwd", true, false},
+		{"no host", "Other", "https:///feed", true, false},
+		{"same url", "Copy", "https://example.com/new-feed", false, true},
+		{"url the feed moved from", "Copy", "https://example.com/feed", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := addFeed(s, alice, tt.feed, tt.url)
+			var invalid *invalidFeedError
+			var exists *feedExistsError
+			if errors.As(err, &invalid) != tt.invalid || errors.As(err, &exists) != tt.exists {
+				t.Errorf("addFeed(%q, %q) error = %v", tt.feed, tt.url, err)
+			}
+		})
+	}
+
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil || len(feeds) != 1 {
+		t.Errorf("feeds after the failed adds = %+v, %v, want only Blog", feeds, err)
+	}
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	bob := dbtest.CreateUser(t, s.db, "bob", false)
+	if _, err := addFeed(s, alice, "Blog", "https://example.com/feed"); err != nil {
+		t.Fatal(err)
+	}
+	s.config.CurrentUserName = "bob"
+
+	steps := []struct {
+		command string
+		args    []string
+		wantErr string
+		follows []string
+	}{
+		{"follow", []string{"blog"}, "", []string{"Blog"}},
+		{"follow", []string{"https://example.com/feed"}, "error creating feed_follow record", []string{"Blog"}},
+		{"rename", []string{"Blog", "My", "Blog"}, "", []string{"My Blog"}},
+		{"unfollow", []string{"Blog"}, "", []string{}},
+		{"unfollow", []string{"Blog"}, "bob isn't following Blog", []string{}},
+		{"unfollow", []string{"Nothing"}, "no feed with url, name or id Nothing", []string{}},
+		{"rename", []string{"Blog", "Mine"}, "bob isn't following Blog", []string{}},
+	}
+	for _, step := range steps {
+		_, err := runTestCommand(s, step.command, step.args...)
+		if step.wantErr == "" && err != nil {
+			t.Errorf("%s %q: %v", step.command, step.args, err)
+		}
+		if step.wantErr != "" && (err == nil || !strings.Contains(err.Error(), step.wantErr)) {
+			t.Errorf("%s %q error = %v, want %q", step.command, step.args, err, step.wantErr)
+		}
+		if got := followedFeeds(t, s, bob); strings.Join(got, "|") != strings.Join(step.follows, "|") {
+			t.Errorf("after %s %q bob follows %q, want %q", step.command, step.args, got, step.follows)
+		}
+	}
+
+	if got := followedFeeds(t, s, alice); len(got) != 1 {
+		t.Errorf("bob's unfollow changed alice's follows to %q", got)
+	}
+}
+
+// Whether err is the error a lookup returns when nothing matched, or with ambiguous, when
+// more than one thing did
+func isLookupError(err error, ambiguous bool) bool {
+	if ambiguous {
+		var ambiguousErr *ambiguousError
+		return errors.As(err, &ambiguousErr)
+	}
+	var lookupErr *lookupError
+	return errors.As(err, &lookupErr)
+}
+
+func TestLookupFeed(t *testing.T) {
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	blog, err := addFeed(s, alice, "Blog", "https://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	news, err := addFeed(s, alice, "News", "https://news.example/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := addFeed(s, alice, "news", "https://other.example/news"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref     string
+		want    uuid.UUID
+		wantErr string
+	}{
+		{"https://example.com/feed", blog.ID, ""},
+		{"BLOG", blog.ID, ""},
+		{shortID(blog.ID), blog.ID, ""},
+		{blog.ID.String(), blog.ID, ""},
+		{"https://news.example/feed", news.ID, ""},
+		{"News", uuid.Nil, "News is ambiguous"},
+		{"https://example.com/other", uuid.Nil, "no feed with url, name or id"},
+		{"%", uuid.Nil, "no feed with url, name or id %"},
+		{"________", uuid.Nil, "no feed with url, name or id ________"},
+	}
+	for _, tt := range tests {
+		feed, err := lookupFeed(s, tt.ref)
+		if tt.wantErr != "" {
+			if !isLookupError(err, strings.Contains(tt.wantErr, "ambiguous")) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("lookupFeed(%q) error = %v, want %q", tt.ref, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || feed.ID != tt.want {
+			t.Errorf("lookupFeed(%q) = %s, %v, want %s", tt.ref, feed.Name, err, tt.want)
+		}
+	}
+}
+
+func TestFeedNeedsOwnerOrAdmin(t *testing.T) {
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	dbtest.CreateUser(t, s.db, "bob", false)
+	dbtest.CreateUser(t, s.db, "root", true)
+	for _, name := range []string{"One", "Two"} {
+		if _, err := addFeed(s, alice, name, "https://example.com/"+strings.ToLower(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		user    string
+		args    []string
+		wantErr string
+	}{
+		{"bob", []string{"edit", "One", "--name", "Mine"}, "only the user who added One or an admin can change it"},
+		{"bob", []string{"delete", "One"}, "only the user who added One or an admin can change it"},
+		{"bob", []string{"fulltext", "One", "on"}, "only the user who added One or an admin can change it"},
+		{"alice", []string{"edit", "--url", "javascript:alert(1)", "One"}, "isn't an http or https url"},
+		{"alice", []string{"edit", "--url", "https://example.com/two", "One"}, "feed merge"},
+		{"alice", []string{"edit", "--name", "   ", "One"}, "a feed needs a name and a url"},
+		{"alice", []string{"edit", "--name", " ", "--url", "https://example.com/new", "One"}, "a feed needs a name and a url"},
+		{"alice", []string{"edit", "--name", "First", "One"}, ""},
+		{"root", []string{"delete", "Two"}, ""},
+	}
+	for _, tt := range tests {
+		s.config.CurrentUserName = tt.user
+		_, err := runTestCommand(s, "feed", tt.args...)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: feed %q: %v", tt.user, tt.args, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: feed %q error = %v, want %q", tt.user, tt.args, err, tt.wantErr)
+		}
+	}
+
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || feeds[0].Name != "First" || feeds[0].Url != "https://example.com/one" {
+		t.Errorf("feeds = %+v, want only First at its old url", feeds)
+	}
+}
+
+func TestLookupPost(t *testing.T) {
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	bob := dbtest.CreateUser(t, s.db, "bob", false)
+	feed, err := addFeed(s, alice, "Blog", "https://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Posts whose ids share more than the usual short id
+	ids := []string{
+		"11111111-0000-4000-8000-000000000000",
+		"11111111-2222-4000-8000-000000000000",
+		"11111111-2223-4000-8000-000000000000",
+		"abcdef12-0000-4000-8000-000000000000",
+	}
+	for i, id := range ids {
+		dbtest.CreatePost(t, s.db, feed, dbtest.Post{ID: uuid.MustParse(id), Title: id, Day: i + 1})
+	}
+
+	wantShort := []string{"11111111-0", "11111111-2222", "11111111-2223", "abcdef12"}
+	for i, id := range ids {
+		short, err := shortPostID(s, alice, uuid.MustParse(id))
+		if err != nil || short != wantShort[i] {
+			t.Errorf("shortPostID(%s) = %q, %v, want %q", id, short, err, wantShort[i])
+			continue
+		}
+		if post, err := lookupPost(s, alice, short); err != nil || post.ID.String() != id {
+			t.Errorf("lookupPost(%q) = %s, %v, want %s", short, post.ID, err, id)
+		}
+	}
+
+	tests := []struct {
+		user    database.User
+		ref     string
+		wantErr string
+	}{
+		{alice, "11111111", "is ambiguous"},
+		{alice, "%", "no post with id %"},
+		{alice, "1111111_", "no post with id 1111111_"},
+		{alice, "5", "no post number 5, you have 4 posts"},
+		{bob, "abcdef12", "no post with id abcdef12"},
+		{bob, ids[0], "no post with id " + ids[0]},
+	}
+	for _, tt := range tests {
+		_, err := lookupPost(s, tt.user, tt.ref)
+		if !isLookupError(err, strings.Contains(tt.wantErr, "ambiguous")) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: lookupPost(%q) error = %v, want %q", tt.user.Name, tt.ref, err, tt.wantErr)
+		}
+	}
+}
+
+func TestImportOutlines(t *testing.T) {
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	bob := dbtest.CreateUser(t, s.db, "bob", false)
+	if _, err := addFeed(s, alice, "Blog", "https://blog.example.com/feed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := addFeed(s, bob, "News", "https://news.example.com/feed"); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := OPML{}
+	err := xml.Unmarshal([]byte(`<opml version="2.0"><body>
+		<outline text="Tech">
+			<outline text="Blog again" xmlUrl="https://blog.example.com/feed"/>
+			<outline text="News" xmlUrl="https://news.example.com/feed"/>
+			<outline text="  " xmlUrl="https://new.example.com/feed"/>
+			<outline text="Script" xmlUrl="javascript:alert(1)"/>
+			<outline text="Files" xmlUrl="file:///etc/passwd"/>
+			<outline text="No host" xmlUrl="https:///feed"/>
+		</outline>
+	</body></opml>`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var counts importCounts
+	for _, outline := range doc.Body.Outlines {
+		importOutline(s, alice, outline, "", &counts)
+	}
+	if counts != (importCounts{imported: 2, following: 1, skipped: 3}) {
+		t.Errorf("counts = %+v, want 2 imported, 1 already followed and 3 skipped", counts)
+	}
+
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, feed := range feeds {
+		urls = append(urls, feed.Url)
+	}
+	slices.Sort(urls)
+	if strings.Join(urls, " ") != "https://blog.example.com/feed https://new.example.com/feed https://news.example.com/feed" {
+		t.Errorf("feeds after the import = %q, want none of the rejected urls", urls)
+	}
+	if got := followedFeeds(t, s, alice); len(got) != 3 {
+		t.Errorf("alice follows %q, want Blog, News and the new feed", got)
+	}
+}
+
+// Registers users through the commands, which keep the logged in user in the config file under $HOME
+func TestUsers(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := newTestState(t)
+
+	steps := []struct {
+		command string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"register", []string{"alice"}, "Username set to alice\n", ""},
+		{"register", []string{"bob"}, "Username set to bob\n", ""},
+		{"register", []string{"alice"}, "", "error creating user"},
+		{"users", nil, "* alice\n* bob (current)\n", ""},
+		{"login", []string{"carol"}, "", "user does not exist"},
+		{"login", []string{"alice"}, "Username set to alice\n", ""},
+		{"users", nil, "* alice (current)\n* bob\n", ""},
+	}
+	for _, step := range steps {
+		out, err := runTestCommand(s, step.command, step.args...)
+		if step.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), step.wantErr) {
+				t.Errorf("%s %q error = %v, want %q", step.command, step.args, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil || out != step.want {
+			t.Errorf("%s %q = %q, %v, want %q", step.command, step.args, out, err, step.want)
+		}
+	}
+
+	if s.config.CurrentUserName != "alice" {
+		t.Errorf("logged in as %q, want alice", s.config.CurrentUserName)
+	}
+	// The first user to register is the admin
+	for name, admin := range map[string]bool{"alice": true, "bob": false} {
+		user, err := s.db.GetUser(context.Background(), name)
+		if err != nil || user.IsAdmin != admin {
+			t.Errorf("%s is admin %v, %v, want %v", name, user.IsAdmin, err, admin)
+		}
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	s := newTestState(t)
+	dbtest.CreateUser(t, s.db, "alice", true)
+	dbtest.CreateUser(t, s.db, "bob", false)
+
+	steps := []struct {
+		user    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"bob", []string{"grant", "bob"}, "", "only admins can change who is an admin"},
+		{"alice", []string{"grant", "bob"}, "bob is now an admin\n", ""},
+		{"alice", []string{"revoke", "alice"}, "", "you can't revoke your own admin rights"},
+		{"bob", []string{"revoke", "alice"}, "alice is no longer an admin\n", ""},
+		{"bob", []string{"grant", "carol"}, "", "no user named carol"},
+		{"bob", []string{"promote", "alice"}, "", "usage:"},
+	}
+	for _, step := range steps {
+		s.config.CurrentUserName = step.user
+		out, err := runTestCommand(s, "admin", step.args...)
+		if step.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), step.wantErr) {
+				t.Errorf("%s: admin %q error = %v, want %q", step.user, step.args, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil || out != step.want {
+			t.Errorf("%s: admin %q = %q, %v, want %q", step.user, step.args, out, err, step.want)
+		}
+	}
+
+	for name, admin := range map[string]bool{"alice": false, "bob": true} {
+		user, err := s.db.GetUser(context.Background(), name)
+		if err != nil || user.IsAdmin != admin {
+			t.Errorf("%s is admin %v, %v, want %v", name, user.IsAdmin, err, admin)
+		}
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	s.config.CurrentUserName = "alice"
+
+	out, err := runTestCommand(s, "token", "ls")
+	if err != nil || out != "You have no tokens, create one with: gator token create <name>\n" {
+		t.Errorf("token ls = %q, %v, want no tokens", out, err)
+	}
+
+	out, err = runTestCommand(s, "token", "create", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := regexp.MustCompile(tokenPrefix + `[0-9a-f]{64}`).FindString(out)
+	if token == "" {
+		t.Fatalf("token create printed %q, want the new token", out)
+	}
+	if user, err := s.db.GetUserByAPIToken(context.Background(), hashToken(token)); err != nil || user.ID != alice.ID {
+		t.Errorf("the printed token belongs to %q, %v, want alice", user.Name, err)
+	}
+	if _, err := runTestCommand(s, "token", "create", "laptop"); err == nil || !strings.Contains(err.Error(), "you already have a token called laptop") {
+		t.Errorf("creating a second laptop token error = %v", err)
+	}
+
+	out, err = runTestCommand(s, "token", "ls")
+	if err != nil || !strings.HasPrefix(out, "  laptop ") || strings.Count(out, "\n") != 1 {
+		t.Errorf("token ls = %q, %v, want only laptop", out, err)
+	}
+
+	out, err = runTestCommand(s, "token", "revoke", "laptop")
+	if err != nil || out != "Revoked laptop\n" {
+		t.Errorf("token revoke = %q, %v", out, err)
+	}
+	if _, err := s.db.GetUserByAPIToken(context.Background(), hashToken(token)); err == nil {
+		t.Error("the revoked token still works")
+	}
+	if _, err := runTestCommand(s, "token", "revoke", "laptop"); err == nil || !strings.Contains(err.Error(), "you have no token called laptop") {
+		t.Errorf("revoking laptop again error = %v", err)
+	}
+}
+
+// Alice follows Blog, which she keeps in her Tech folder, and bob's News
+func newFollowingTestState(t *testing.T) (*state, database.Feed, database.Feed) {
+	t.Helper()
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	bob := dbtest.CreateUser(t, s.db, "bob", false)
+	s.config.CurrentUserName = "alice"
+
+	blog, err := addFeed(s, alice, "Blog", "https://blog.example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	news, err := addFeed(s, bob, "News", "https://news.example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := followFeed(s, alice, news.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runTestCommand(s, "folder", "create", "Tech"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runTestCommand(s, "folder", "add", "Blog", "Tech"); err != nil {
+		t.Fatal(err)
+	}
+	return s, blog, news
+}
+
+func TestFolder(t *testing.T) {
+	s, _, _ := newFollowingTestState(t)
+
+	steps := []struct {
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{[]string{"ls"}, "* Tech\n", ""},
+		{[]string{"create", "tech"}, "", "folder tech already exists"},
+		{[]string{"create", "News"}, "Created folder News\n", ""},
+		{[]string{"add", "News", "news"}, "Moved News to News\n", ""},
+		{[]string{"add", "News", "Sport"}, "", "no folder named Sport"},
+		{[]string{"ls"}, "* News\n* Tech\n", ""},
+		{[]string{"rm", "Tech", "Blog"}, "Removed Blog from Tech\n", ""},
+		{[]string{"rm", "News"}, "Deleted folder News\n", ""},
+		{[]string{"ls"}, "* Tech\n", ""},
+		{[]string{"rename", "Tech"}, "", "usage:"},
+	}
+	for _, step := range steps {
+		out, err := runTestCommand(s, "folder", step.args...)
+		if step.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), step.wantErr) {
+				t.Errorf("folder %q error = %v, want %q", step.args, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil || out != step.want {
+			t.Errorf("folder %q = %q, %v, want %q", step.args, out, err, step.want)
+		}
+	}
+
+	// Deleting News put its feed back with the unfiled ones, so no headings are needed
+	out, err := runTestCommand(s, "following")
+	if err != nil || strings.Contains(out, "Unfiled") || !strings.Contains(out, "Blog") || !strings.Contains(out, "News") {
+		t.Errorf("following after emptying the folders = %q, %v", out, err)
+	}
+}
+
+func TestFollowing(t *testing.T) {
+	s, blog, news := newFollowingTestState(t)
+
+	want := fmt.Sprintf("alice is following:\n\nTech\n  -%s  Blog\nUnfiled\n  -%s  News\n", shortID(blog.ID), shortID(news.ID))
+	if out, err := runTestCommand(s, "following"); err != nil || out != want {
+		t.Errorf("following = %q, %v, want %q", out, err, want)
+	}
+
+	err := s.db.SetFeedActive(context.Background(), database.SetFeedActiveParams{
+		ID:            news.ID,
+		Active:        false,
+		DeactivatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt:     time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The notice is shown once, the feed stays marked as gone
+	out, err := runTestCommand(s, "following")
+	if err != nil || !strings.HasPrefix(out, "! News is gone and won't be fetched anymore") || !strings.HasSuffix(out, "  News (gone)\n") {
+		t.Errorf("following after News went away = %q, %v", out, err)
+	}
+	out, err = runTestCommand(s, "following")
+	if err != nil || strings.Contains(out, "!") || !strings.HasSuffix(out, "  News (gone)\n") {
+		t.Errorf("following a second time = %q, %v, want no notice", out, err)
+	}
+}
+
+func TestListFeeds(t *testing.T) {
+	s, blog, news := newFollowingTestState(t)
+
+	want := fmt.Sprintf("- Feed: Blog\n  ID: %s\n  URL: https://blog.example.com/feed\n  Created by: alice\n\n", shortID(blog.ID)) +
+		fmt.Sprintf("- Feed: News\n  ID: %s\n  URL: https://news.example.com/feed\n  Created by: bob\n\n", shortID(news.ID))
+	if out, err := runTestCommand(s, "feeds"); err != nil || out != want {
+		t.Errorf("feeds = %q, %v, want %q", out, err, want)
+	}
+}
+
+func TestExport(t *testing.T) {
+	s, _, _ := newFollowingTestState(t)
+	if _, err := runTestCommand(s, "rename", "News", "Headlines"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runTestCommand(s, "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	if printed, err := runTestCommand(s, "export", path); err != nil || printed != "" {
+		t.Fatalf("export to a file printed %q, %v", printed, err)
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exported := range []string{out, string(file)} {
+		var doc OPML
+		if err := xml.Unmarshal([]byte(exported), &doc); err != nil {
+			t.Fatalf("%v in %s", err, exported)
+		}
+		var got []string
+		for _, outline := range doc.Body.Outlines {
+			if outline.XMLURL != "" {
+				got = append(got, outline.Text+" "+outline.XMLURL)
+			}
+			for _, feed := range outline.Outlines {
+				got = append(got, outline.Text+"/"+feed.Text+" "+feed.XMLURL)
+			}
+		}
+		want := "Tech/Blog https://blog.example.com/feed|Headlines https://news.example.com/feed"
+		if strings.Join(got, "|") != want {
+			t.Errorf("exported %q, want %q", got, want)
+		}
+	}
+}
+
+// Alice follows Blog, in her Tech folder, and News. Newest first their posts are Generics, Errors,
+// Election, Modules and Weather.
+func newBrowseTestState(t *testing.T) (*state, map[string]database.Post) {
+	t.Helper()
+	s, blog, news := newFollowingTestState(t)
+	posts := map[string]database.Post{
+		"Generics": dbtest.CreatePost(t, s.db, blog, dbtest.Post{Title: "Generics", Day: 5, Authors: []string{"Doe, Jane"}, Categories: []string{"Go", "Language"}}),
+		"Errors":   dbtest.CreatePost(t, s.db, blog, dbtest.Post{Title: "Errors", Day: 4, Authors: []string{"Rob"}, Categories: []string{"Go"}}),
+		"Election": dbtest.CreatePost(t, s.db, news, dbtest.Post{Title: "Election", Day: 3, Categories: []string{"Politics"}}),
+		"Weather":  dbtest.CreatePost(t, s.db, news, dbtest.Post{Title: "Weather", Day: 1}),
+	}
+	// The only post with the full article as well as a summary
+	modules, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   dbtest.Time,
+		UpdatedAt:   dbtest.Time,
+		Title:       "Modules",
+		Url:         "https://blog.example.com/feed/modules",
+		Description: "About Modules",
+		Content:     "Everything about Modules",
+		PublishedAt: time.Date(2026, 10, 2, 9, 0, 0, 0, time.UTC).Format(time.RFC3339),
+		FeedID:      blog.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts["Modules"] = modules
+	return s, posts
+}
+
+var (
+	browsedPost = regexp.MustCompile(`Read more: https://\w+\.example\.com/feed/(\w+)`)
+	moreHint    = regexp.MustCompile(`More: gator browse --before (\S+)`)
+)
+
+// Returns the titles of the posts browse printed, and the id in the hint for the next page
+func browsed(out string) (titles string, before string) {
+	var got []string
+	for _, match := range browsedPost.FindAllStringSubmatch(out, -1) {
+		got = append(got, match[1])
+	}
+	if match := moreHint.FindStringSubmatch(out); match != nil {
+		before = match[1]
+	}
+	return strings.Join(got, "|"), before
+}
+
+func TestBrowse(t *testing.T) {
+	s, posts := newBrowseTestState(t)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "generics|errors|election"},
+		{[]string{"10"}, "generics|errors|election|modules|weather"},
+		{[]string{"--folder", "tech", "10"}, "generics|errors|modules"},
+		{[]string{"--author", "doe, j"}, "generics"},
+		{[]string{"10", "--category", "Go"}, "generics|errors"},
+		{[]string{"--category", "Sport"}, ""},
+	}
+	for _, tt := range tests {
+		out, err := runTestCommand(s, "browse", tt.args...)
+		if got, _ := browsed(out); err != nil || got != tt.want {
+			t.Errorf("browse %q = %q, %v, want %q", tt.args, got, err, tt.want)
+		}
+	}
+
+	out, err := runTestCommand(s, "browse", "--author", "Jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := shortPostID(s, alice, posts["Generics"].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Generics\n", id + "    Blog    2026-10-05T09:00:00Z\n", "by Doe, Jane\n", "tags: Go, Language\n", "About Generics", "(gator open " + id + ")"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("browse --author Jane = %q, want it to contain %q", out, want)
+		}
+	}
+
+	// Only Modules has an article to show in full, the others still show their summary
+	out, err = runTestCommand(s, "browse", "--full", "--folder", "Tech", "10")
+	if err != nil || !strings.Contains(out, "Everything about Modules") || strings.Contains(out, "About Modules") || !strings.Contains(out, "About Errors") {
+		t.Errorf("browse --full = %q, %v, want the whole Modules article", out, err)
+	}
+
+	for _, args := range [][]string{{"0"}, {"many"}} {
+		if _, err := runTestCommand(s, "browse", args...); err == nil {
+			t.Errorf("browse %q succeeded, want an error about the limit", args)
+		}
+	}
+}
+
+func TestBrowsePages(t *testing.T) {
+	s, posts := newBrowseTestState(t)
+
+	// Each page ends with the hint for the next, until a page comes up short
+	var pages []string
+	args := []string{"2"}
+	for range 5 {
+		out, err := runTestCommand(s, "browse", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles, before := browsed(out)
+		pages = append(pages, titles)
+		if before == "" {
+			break
+		}
+		args = []string{"2", "--before", before}
+	}
+	if got := strings.Join(pages, " / "); got != "generics|errors / election|modules / weather" {
+		t.Errorf("pages = %q", got)
+	}
+
+	// The hint names the last post on the page
+	out, err := runTestCommand(s, "browse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, before := browsed(out)
+	if !strings.HasPrefix(posts["Election"].ID.String(), before) || len(before) < 8 {
+		t.Errorf("the default page's hint is --before %q, want Election's id %s", before, posts["Election"].ID)
+	}
+
+	// Starting from a post in a folder's page only goes on within the folder
+	out, err = runTestCommand(s, "browse", "--folder", "Tech", "--before", posts["Errors"].ID.String())
+	if got, _ := browsed(out); err != nil || got != "modules" {
+		t.Errorf("browse --folder Tech after Errors = %q, %v, want modules", got, err)
+	}
+
+	if _, err := runTestCommand(s, "browse", "--before", uuid.NewString()); !isLookupError(err, false) {
+		t.Errorf("browse --before an unknown post error = %v", err)
+	}
+}
+
+func TestOpenCopy(t *testing.T) {
+	s, posts := newBrowseTestState(t)
+	post := posts["Errors"]
+
+	out, err := runTestCommand(s, "open", "--copy", post.ID.String()[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(post.Url)) + "\a" + "Copied " + post.Url + " to the clipboard\n"
+	if out != want {
+		t.Errorf("open --copy = %q, want %q", out, want)
+	}
+
+	if _, err := runTestCommand(s, "open", "--copy", uuid.NewString()); !isLookupError(err, false) {
+		t.Errorf("open --copy on an unknown post error = %v", err)
+	}
+	if _, err := runTestCommand(s, "open"); err == nil {
+		t.Error("open with no post succeeded")
+	}
+}
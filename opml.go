@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []OPMLOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+// An outline is either a feed, when it has an xmlUrl, or a folder holding more outlines
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// Writes the user's subscriptions as OPML, with each folder as an outline around its feeds
+func handlerExport(s *state, cmd command, user database.User) error {
+	following, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting followed feeds: %w", err)
+	}
+
+	folders, err := s.db.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting folders: %w", err)
+	}
+
+	doc := OPML{Version: "2.0"}
+	doc.Head.Title = fmt.Sprintf("Gator subscriptions for %s", user.Name)
+	doc.Head.DateCreated = time.Now().Format(time.RFC1123Z)
+
+	// Empty folders are exported too so the structure survives a round trip
+	folderOutlines := make(map[uuid.UUID]*OPMLOutline)
+	outlines := make([]OPMLOutline, len(folders))
+	for i, folder := range folders {
+		outlines[i] = OPMLOutline{Text: folder.Name, Title: folder.Name}
+		folderOutlines[folder.ID] = &outlines[i]
+	}
+
+	var unfiled []OPMLOutline
+	for _, follow := range following {
+		feed := OPMLOutline{
+			Text:   follow.FeedName,
+			Title:  follow.FeedName,
+			Type:   "rss",
+			XMLURL: follow.FeedUrl,
+		}
+		if folder, ok := folderOutlines[follow.FolderID.UUID]; follow.FolderID.Valid && ok {
+			folder.Outlines = append(folder.Outlines, feed)
+		} else {
+			unfiled = append(unfiled, feed)
+		}
+	}
+	doc.Body.Outlines = append(outlines, unfiled...)
+
+	out := s.out
+	if len(cmd.arguments) > 0 {
+		file, err := os.Create(cmd.arguments[0])
+		if err != nil {
+			return fmt.Errorf("error creating file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	fmt.Fprint(out, xml.Header)
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("error writing opml: %w", err)
+	}
+	fmt.Fprintln(out)
+
+	return nil
+}
+
+// Reads an OPML file, adding any feeds Gator doesn't know yet, following them and filing them into folders
+func handlerImport(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("expecting 1 argument (opml file)")
+	}
+
+	data, err := os.ReadFile(cmd.arguments[0])
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+
+	doc := OPML{}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("error parsing opml: %w", err)
+	}
+
+	var counts importCounts
+	for _, outline := range doc.Body.Outlines {
+		importOutline(s, user, outline, "", &counts)
+	}
+
+	fmt.Fprintf(s.out, "Imported %d feeds for %s", counts.imported, user.Name)
+	if counts.following > 0 {
+		fmt.Fprintf(s.out, ", %d already followed", counts.following)
+	}
+	if counts.skipped > 0 {
+		fmt.Fprintf(s.out, ", skipped %d", counts.skipped)
+	}
+	fmt.Fprintln(s.out)
+	return nil
+}
+
+// What happened to the feeds in an OPML file
+type importCounts struct {
+	// Newly followed
+	imported int
+	// Followed before the import, though they may have been filed into a folder
+	following int
+	// Rejected or failed, and left out
+	skipped int
+}
+
+// Imports a feed outline, or every feed under a folder outline. Nested folders are flattened
+// into the innermost folder since Gator folders don't nest.
+func importOutline(s *state, user database.User, outline OPMLOutline, folderName string, counts *importCounts) {
+	name := strings.TrimSpace(outline.Title)
+	if name == "" {
+		name = strings.TrimSpace(outline.Text)
+	}
+
+	if outline.XMLURL == "" {
+		for _, child := range outline.Outlines {
+			importOutline(s, user, child, name, counts)
+		}
+		return
+	}
+
+	if name == "" {
+		name = outline.XMLURL
+	}
+
+	// Each feed goes in whole or not at all, without one bad feed holding up the rest
+	var feed database.Feed
+	var followed bool
+	err := s.inTx(func(s *state) error {
+		var err error
+		feed, followed, err = importFeed(s, user, name, outline.XMLURL)
+		if err != nil || folderName == "" {
+			return err
+		}
+
+		folder, err := getFolder(s, user, folderName)
+		if err != nil {
+			folder, err = createFolder(s, user, folderName)
+		}
+		if err != nil {
+			return fmt.Errorf("error creating folder %s: %w", folderName, err)
+		}
+		return setFollowFolder(s, user, feed, uuid.NullUUID{UUID: folder.ID, Valid: true})
+	})
+	if err != nil {
+		fmt.Fprintf(s.out, "error importing %s: %v\n", outline.XMLURL, err)
+		counts.skipped++
+		return
+	}
+
+	if !followed {
+		counts.following++
+		return
+	}
+	fmt.Fprintf(s.out, "  -%s  %s\n", shortID(feed.ID), feed.Name)
+	counts.imported++
+}
+
+// Finds or creates the feed at url and makes sure the user follows it, reporting whether
+// the follow is new
+func importFeed(s *state, user database.User, name, url string) (database.Feed, bool, error) {
+	var feed database.Feed
+
+	found, err := s.db.URLLookup(context.Background(), url)
+	switch {
+	case err == nil:
+		feed, err = s.db.GetFeedByID(context.Background(), found.ID)
+		if err != nil {
+			return feed, false, err
+		}
+
+		// PostgreSQL gives up on a transaction after any failed statement,
+		// so look for the follow instead of letting a duplicate insert fail
+		var follows []database.GetFeedFollowsForUserRow
+		follows, err = s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+		if err != nil {
+			return feed, false, err
+		}
+		for _, follow := range follows {
+			if follow.FeedID == feed.ID {
+				return feed, false, nil
+			}
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		err = validateFeed(name, url)
+		if err != nil {
+			return feed, false, err
+		}
+		feed, err = s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+			ID:            uuid.New(),
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			Name:          name,
+			Url:           url,
+			UserID:        user.ID,
+			LastFetchedAt: time.Now(),
+		})
+	}
+	if err != nil {
+		return feed, false, err
+	}
+
+	_, err = s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		return feed, false, err
+	}
+
+	return feed, true, nil
+}
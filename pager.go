@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const defaultPager = "less -R"
+
+// Output that is streamed through the user's pager, or straight to the state's output when paging is off.
+// Once the pager exits every write fails, so callers can stop producing output early.
+type pager struct {
+	out io.WriteCloser
+	cmd *exec.Cmd
+	err error
+}
+
+// Starts the pager the way git does: only when the output is a terminal, using the pager from the config,
+// then $PAGER, then less. A pager set to "cat" turns paging off.
+func startPager(s *state, disabled bool) *pager {
+	command := s.config.Pager
+	if command == "" {
+		command = os.Getenv("PAGER")
+	}
+	if command == "" {
+		command = defaultPager
+	}
+
+	fields := strings.Fields(command)
+	stdout, isFile := s.out.(*os.File)
+	if disabled || !isFile || !term.IsTerminal(int(stdout.Fd())) || len(fields) == 0 || fields[0] == "cat" {
+		return &pager{out: nopCloser{s.out}}
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	// Quit when everything fits on one screen, keep colors, and leave the output on screen afterwards
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return &pager{out: nopCloser{stdout}}
+	}
+	if err := cmd.Start(); err != nil {
+		return &pager{out: nopCloser{stdout}}
+	}
+
+	// Ctrl+C belongs to the pager while it's running, we stop once it closes the pipe
+	signal.Ignore(os.Interrupt)
+
+	return &pager{out: in, cmd: cmd}
+}
+
+func (p *pager) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	n, err := p.out.Write(b)
+	if err != nil {
+		p.err = err
+	}
+	return n, err
+}
+
+// Reports whether the pager has gone away and further output would be thrown away
+func (p *pager) Closed() bool {
+	return p.err != nil
+}
+
+// Closes the pager's input and waits for the user to quit it
+func (p *pager) Close() error {
+	err := p.out.Close()
+	if p.cmd != nil {
+		p.cmd.Wait()
+		signal.Reset(os.Interrupt)
+	}
+	return err
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
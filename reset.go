@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/archive"
+	"github.com/Daxin319/Gator/internal/database"
+)
+
+const resetUsage = `usage:
+  reset [--yes]                 delete everything: users, feeds, posts and folders
+  reset --posts [--yes]         delete every post, keeping users, feeds and follows
+  reset --feeds [--yes]         delete every feed along with its posts and follows
+  reset --user <name> [--yes]   delete a user and everything of theirs, the feeds they added become yours`
+
+func handlerReset(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("reset", flag.ExitOnError)
+	posts := fs.Bool("posts", false, "only delete posts")
+	feeds := fs.Bool("feeds", false, "only delete feeds")
+	userName := fs.String("user", "", "only delete this user")
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	args := parseFlags(fs, cmd.arguments)
+
+	scopes := 0
+	for _, set := range []bool{*posts, *feeds, *userName != ""} {
+		if set {
+			scopes++
+		}
+	}
+	if len(args) != 0 || scopes > 1 {
+		return errors.New(resetUsage)
+	}
+
+	if !user.IsAdmin {
+		return fmt.Errorf("only admins can reset the database")
+	}
+	if *userName == user.Name {
+		return fmt.Errorf("you can't delete yourself, have another admin do it")
+	}
+
+	var question string
+	var reset func(s *state) (string, error)
+	switch {
+	case *posts:
+		question = "Delete every post, along with read and starred states?"
+		reset = func(s *state) (string, error) {
+			deleted, err := s.db.DeleteAllPosts(context.Background())
+			return fmt.Sprintf("Deleted %d posts", deleted), err
+		}
+	case *feeds:
+		question = "Delete every feed, along with its posts and follows?"
+		reset = func(s *state) (string, error) {
+			deleted, err := s.db.DeleteAllFeeds(context.Background())
+			return fmt.Sprintf("Deleted %d feeds", deleted), err
+		}
+	case *userName != "":
+		target, err := s.db.GetUser(context.Background(), *userName)
+		if err != nil {
+			return fmt.Errorf("no user named %s", *userName)
+		}
+		question = fmt.Sprintf("Delete %s and everything of theirs? The feeds they added stay, and become yours.", *userName)
+		reset = func(s *state) (string, error) {
+			// Feeds are shared, deleting the user would take them away from everyone following them
+			reassigned, err := s.db.ReassignFeeds(context.Background(), database.ReassignFeedsParams{
+				NewUserID: user.ID,
+				UpdatedAt: time.Now(),
+				OldUserID: target.ID,
+			})
+			if err != nil {
+				return "", fmt.Errorf("error reassigning feeds: %w", err)
+			}
+			_, err = s.db.DeleteUser(context.Background(), *userName)
+			return fmt.Sprintf("Deleted %s, the %d feeds they added are now yours", *userName, reassigned), err
+		}
+	default:
+		question = "Delete everything, every user, feed, post and folder?"
+		reset = func(s *state) (string, error) {
+			return "Database reset!", s.db.ResetUsers(context.Background())
+		}
+	}
+
+	if !*yes && !confirm(s, question) {
+		return errors.New("Aborted, nothing was deleted.")
+	}
+
+	// Whatever happens next, there's a way back
+	path, err := snapshotPath("reset")
+	if err != nil {
+		return err
+	}
+	stats, err := archive.WriteFile(context.Background(), s.db, path)
+	if err != nil {
+		return fmt.Errorf("error saving snapshot, nothing was deleted: %w", err)
+	}
+	fmt.Fprintf(s.out, "Saved a snapshot of %s to %s\n", stats, path)
+
+	var result string
+	err = s.inTx(func(s *state) error {
+		var err error
+		result, err = reset(s)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error resetting database: %w", err)
+	}
+	fmt.Fprintln(s.out, result)
+	return nil
+}
+
+// Snapshots are kept in ~/.gator/snapshots, named after what they were taken for and when
+func snapshotPath(reason string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(home, ".gator", "snapshots", fmt.Sprintf("%s-%s", reason, time.Now().Format("20060102-150405")))
+
+	// Don't overwrite a snapshot taken earlier in the same second
+	path := base + ".jsonl.gz"
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		path = fmt.Sprintf("%s-%d.jsonl.gz", base, i)
+	}
+}
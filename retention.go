@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/config"
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// How often agg prunes old posts, fetching happens far more often than anything gets old enough to go
+const pruneInterval = time.Hour
+
+// The retention settings that apply to a feed, its own overrides win over the config file.
+// A post is kept while either limit keeps it, so a feed that sets either one to 0 keeps
+// everything, whatever the config file says.
+func retentionFor(global config.Retention, feed database.Feed) config.Retention {
+	if (feed.RetentionDays.Valid && feed.RetentionDays.Int32 == 0) || (feed.RetentionPosts.Valid && feed.RetentionPosts.Int32 == 0) {
+		return config.Retention{Archive: global.Archive}
+	}
+	policy := global
+	if feed.RetentionDays.Valid {
+		policy.Days = int(feed.RetentionDays.Int32)
+	}
+	if feed.RetentionPosts.Valid {
+		policy.Posts = int(feed.RetentionPosts.Int32)
+	}
+	return policy
+}
+
+func describeRetention(policy config.Retention) string {
+	switch {
+	case policy.Days > 0 && policy.Posts > 0:
+		return fmt.Sprintf("keep %d days or the newest %d posts", policy.Days, policy.Posts)
+	case policy.Days > 0:
+		return fmt.Sprintf("keep %d days", policy.Days)
+	case policy.Posts > 0:
+		return fmt.Sprintf("keep the newest %d posts", policy.Posts)
+	default:
+		return "keep everything"
+	}
+}
+
+type pruned struct {
+	feed  database.Feed
+	posts int
+}
+
+// Removes the posts that have outlived their feed's retention settings, or with dryRun only
+// counts them. Starred posts are always kept. Pruned urls are remembered so agg doesn't
+// fetch the same posts again, and with archive set the posts are moved to archived_posts.
+func prunePosts(s *state, dryRun bool) ([]pruned, error) {
+	if s.config.Retention.Days < 0 || s.config.Retention.Posts < 0 {
+		return nil, fmt.Errorf("retention days and posts in the config file can't be negative")
+	}
+
+	feeds, err := s.db.ListFeeds(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error getting feeds: %w", err)
+	}
+
+	var results []pruned
+	for _, feed := range feeds {
+		policy := retentionFor(s.config.Retention, feed)
+		if policy.Days <= 0 && policy.Posts <= 0 {
+			continue
+		}
+
+		params := database.GetPostsToPruneParams{FeedID: feed.ID}
+		if policy.Days > 0 {
+			cutoff := time.Now().UTC().AddDate(0, 0, -policy.Days)
+			params.PublishedBefore = sql.NullString{String: cutoff.Format(time.RFC3339), Valid: true}
+		}
+		if policy.Posts > 0 {
+			params.KeepNewest = sql.NullInt64{Int64: int64(policy.Posts), Valid: true}
+		}
+
+		// Each feed is pruned on its own, a failure leaves that feed's posts as they were
+		var ids []uuid.UUID
+		err = s.inTx(func(s *state) error {
+			var err error
+			ids, err = s.db.GetPostsToPrune(context.Background(), params)
+			if err != nil {
+				return fmt.Errorf("error finding posts to prune in %s: %w", feed.Name, err)
+			}
+			if dryRun {
+				return nil
+			}
+			for _, id := range ids {
+				err = prunePost(s, id)
+				if err != nil {
+					return fmt.Errorf("error pruning posts in %s: %w", feed.Name, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return results, err
+		}
+		if len(ids) > 0 {
+			results = append(results, pruned{feed: feed, posts: len(ids)})
+		}
+	}
+	return results, nil
+}
+
+func prunePost(s *state, id uuid.UUID) error {
+	now := time.Now()
+	if s.config.Retention.Archive {
+		err := s.db.ArchivePost(context.Background(), database.ArchivePostParams{ID: id, ArchivedAt: now})
+		if err != nil {
+			return err
+		}
+	}
+	err := s.db.AddPrunedPost(context.Background(), database.AddPrunedPostParams{ID: id, PrunedAt: now})
+	if err != nil {
+		return err
+	}
+	// Authors, categories and read states go with the post through the foreign keys
+	return s.db.DeletePost(context.Background(), id)
+}
+
+// Prunes posts for agg, logging what was removed rather than stopping the aggregator
+func prunePostsForAgg(s *state) {
+	results, err := prunePosts(s, false)
+	for _, r := range results {
+		log.Printf("pruned %d posts from %s", r.posts, r.feed.Name)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func handlerPrune(s *state, dryRun bool) error {
+	results, err := prunePosts(s, dryRun)
+
+	verb := "Pruned"
+	if dryRun {
+		verb = "Would prune"
+	} else if s.config.Retention.Archive {
+		verb = "Archived"
+	}
+
+	total := 0
+	for _, r := range results {
+		fmt.Fprintf(s.out, "  %s  %-30s  %d posts  (%s)\n", shortID(r.feed.ID), r.feed.Name, r.posts, describeRetention(retentionFor(s.config.Retention, r.feed)))
+		total += r.posts
+	}
+	if err != nil {
+		return err
+	}
+
+	if total == 0 {
+		fmt.Fprintln(s.out, "Nothing to prune.")
+		return nil
+	}
+	fmt.Fprintf(s.out, "%s %d posts from %d feeds\n", verb, total, len(results))
+	return nil
+}
+
+const feedRetentionUsage = `usage:
+  feed retention <feed>                          show the retention settings that apply to a feed
+  feed retention <feed> [--days N] [--posts N]   override how long the feed's posts are kept, 0 for either keeps them forever
+  feed retention <feed> --default                go back to the settings in the config file`
+
+func handlerFeedRetention(s *state, args []string, user database.User) error {
+	fs := flag.NewFlagSet("feed retention", flag.ExitOnError)
+	days := fs.Int("days", 0, "keep posts this many days, 0 keeps them forever")
+	posts := fs.Int("posts", 0, "keep at least this many of the newest posts, 0 keeps them forever")
+	useDefault := fs.Bool("default", false, "use the config file's settings")
+	args = parseFlags(fs, args)
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if len(args) != 1 || (*useDefault && (set["days"] || set["posts"])) {
+		return errors.New(feedRetentionUsage)
+	}
+	if *days < 0 || *posts < 0 {
+		return fmt.Errorf("--days and --posts can't be negative")
+	}
+
+	if len(set) == 0 {
+		feed, err := lookupFeed(s, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "%s: %s\n", feed.Name, describeRetention(retentionFor(s.config.Retention, feed)))
+		if !feed.RetentionDays.Valid && !feed.RetentionPosts.Valid {
+			fmt.Fprintln(s.out, "Using the settings in the config file.")
+		}
+		return nil
+	}
+
+	feed, err := getManagedFeed(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	params := database.SetFeedRetentionParams{
+		ID:             feed.ID,
+		RetentionDays:  feed.RetentionDays,
+		RetentionPosts: feed.RetentionPosts,
+		UpdatedAt:      time.Now(),
+	}
+	if *useDefault {
+		params.RetentionDays = sql.NullInt32{}
+		params.RetentionPosts = sql.NullInt32{}
+	}
+	if set["days"] {
+		params.RetentionDays = sql.NullInt32{Int32: int32(*days), Valid: true}
+	}
+	if set["posts"] {
+		params.RetentionPosts = sql.NullInt32{Int32: int32(*posts), Valid: true}
+	}
+
+	err = s.db.SetFeedRetention(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("error updating feed: %w", err)
+	}
+
+	feed.RetentionDays = params.RetentionDays
+	feed.RetentionPosts = params.RetentionPosts
+	fmt.Fprintf(s.out, "%s: %s\n", feed.Name, describeRetention(retentionFor(s.config.Retention, feed)))
+	return nil
+}
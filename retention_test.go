@@ -0,0 +1,119 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/config"
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/database/dbtest"
+)
+
+func TestPrunePosts(t *testing.T) {
+	s := newTestState(t)
+	s.config.Retention = config.Retention{Days: 30, Posts: 1}
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	s.config.CurrentUserName = "alice"
+
+	// Every feed has three posts fetched just now, Recent's published in the last few hours
+	// and the rest's years ago
+	for _, name := range []string{"Old", "Recent", "Forever", "All"} {
+		feed, err := addFeed(s, alice, name, "https://example.com/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range 3 {
+			published := time.Date(2020, 1, i+1, 9, 0, 0, 0, time.UTC)
+			if name == "Recent" {
+				published = time.Now().UTC().Add(-time.Duration(i+1) * time.Hour)
+			}
+			dbtest.CreatePost(t, s.db, feed, dbtest.Post{
+				Title:       fmt.Sprintf("%s %d", name, i),
+				PublishedAt: published,
+				FetchedAt:   time.Now(),
+			})
+		}
+	}
+
+	if _, err := runTestCommand(s, "feed", "retention", "Forever", "--days", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runTestCommand(s, "feed", "retention", "All", "--posts", "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := prunePosts(s, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].feed.Name != "Old" || results[0].posts != 2 {
+		t.Errorf("pruned %+v, want 2 posts from Old only", results)
+	}
+
+	left := map[string]int{}
+	for _, post := range testPosts(t, s, alice) {
+		left[post.FeedTitle]++
+	}
+	want := map[string]int{"Old": 1, "Recent": 3, "Forever": 3, "All": 3}
+	for name, count := range want {
+		if left[name] != count {
+			t.Errorf("%s has %d posts left, want %d", name, left[name], count)
+		}
+	}
+}
+
+func TestRetentionFor(t *testing.T) {
+	global := config.Retention{Days: 30, Posts: 100}
+	tests := []struct {
+		days  sql.NullInt32
+		posts sql.NullInt32
+		want  string
+	}{
+		{sql.NullInt32{}, sql.NullInt32{}, "keep 30 days or the newest 100 posts"},
+		{sql.NullInt32{Int32: 7, Valid: true}, sql.NullInt32{}, "keep 7 days or the newest 100 posts"},
+		{sql.NullInt32{}, sql.NullInt32{Int32: 10, Valid: true}, "keep 30 days or the newest 10 posts"},
+		{sql.NullInt32{Int32: 0, Valid: true}, sql.NullInt32{}, "keep everything"},
+		{sql.NullInt32{}, sql.NullInt32{Int32: 0, Valid: true}, "keep everything"},
+	}
+	for _, tt := range tests {
+		feed := database.Feed{RetentionDays: tt.days, RetentionPosts: tt.posts}
+		if got := describeRetention(retentionFor(global, feed)); got != tt.want {
+			t.Errorf("days %+v, posts %+v: %q, want %q", tt.days, tt.posts, got, tt.want)
+		}
+	}
+}
+
+func TestPruneCommand(t *testing.T) {
+	s := newTestState(t)
+	s.config.Retention = config.Retention{Days: 30, Posts: 1}
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	feed, err := addFeed(s, alice, "Old", "https://example.com/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		dbtest.CreatePost(t, s.db, feed, dbtest.Post{Title: fmt.Sprintf("Old %d", i), PublishedAt: time.Date(2020, 1, i+1, 9, 0, 0, 0, time.UTC)})
+	}
+
+	listed := fmt.Sprintf("  %s  %-30s  2 posts  (keep 30 days or the newest 1 posts)\n", shortID(feed.ID), "Old")
+	steps := []struct {
+		args []string
+		want string
+		left int
+	}{
+		{[]string{"prune", "--dry-run"}, listed + "Would prune 2 posts from 1 feeds\n", 3},
+		{[]string{"prune"}, listed + "Pruned 2 posts from 1 feeds\n", 1},
+		{[]string{"prune"}, "Nothing to prune.\n", 1},
+	}
+	for _, step := range steps {
+		out, err := runTestCommand(s, "db", step.args...)
+		if err != nil || out != step.want {
+			t.Errorf("db %q = %q, %v, want %q", step.args, out, err, step.want)
+		}
+		if left := len(testPosts(t, s, alice)); left != step.left {
+			t.Errorf("after db %q %d posts are left, want %d", step.args, left, step.left)
+		}
+	}
+}
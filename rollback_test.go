@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Daxin319/Gator/internal/config"
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/database/dbtest"
+	"github.com/google/uuid"
+)
+
+var errInjected = errors.New("injected failure")
+
+// A Store where one method fails once it has been called a set number of times,
+// in or out of a transaction, to check that nothing is left half done
+type failingStore struct {
+	database.Store
+	method string
+	// Calls to method that still succeed, shared with the stores handed to transactions
+	remaining *int
+}
+
+func failAfter(s database.Store, method string, calls int) *failingStore {
+	return &failingStore{Store: s, method: method, remaining: &calls}
+}
+
+func (f *failingStore) fail(method string) error {
+	if method != f.method {
+		return nil
+	}
+	if *f.remaining > 0 {
+		*f.remaining--
+		return nil
+	}
+	return fmt.Errorf("%s: %w", method, errInjected)
+}
+
+func (f *failingStore) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return f.Store.InTx(ctx, func(tx database.Store) error {
+		return fn(&failingStore{Store: tx, method: f.method, remaining: f.remaining})
+	})
+}
+
+func (f *failingStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	if err := f.fail("CreateFeedFollow"); err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+	return f.Store.CreateFeedFollow(ctx, arg)
+}
+
+func (f *failingStore) MoveFeedPosts(ctx context.Context, arg database.MoveFeedPostsParams) (int64, error) {
+	if err := f.fail("MoveFeedPosts"); err != nil {
+		return 0, err
+	}
+	return f.Store.MoveFeedPosts(ctx, arg)
+}
+
+func (f *failingStore) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	if err := f.fail("DeleteFeed"); err != nil {
+		return err
+	}
+	return f.Store.DeleteFeed(ctx, id)
+}
+
+func (f *failingStore) AddFeedURLHistory(ctx context.Context, arg database.AddFeedURLHistoryParams) error {
+	if err := f.fail("AddFeedURLHistory"); err != nil {
+		return err
+	}
+	return f.Store.AddFeedURLHistory(ctx, arg)
+}
+
+func (f *failingStore) DeleteUser(ctx context.Context, name string) (int64, error) {
+	if err := f.fail("DeleteUser"); err != nil {
+		return 0, err
+	}
+	return f.Store.DeleteUser(ctx, name)
+}
+
+func (f *failingStore) ResetUsers(ctx context.Context) error {
+	if err := f.fail("ResetUsers"); err != nil {
+		return err
+	}
+	return f.Store.ResetUsers(ctx)
+}
+
+// Every row in the database, to compare before and after something that should have changed nothing
+func dumpStore(t *testing.T, s database.Store) string {
+	t.Helper()
+	ctx := context.Background()
+	var out strings.Builder
+	dump := func(name string, rows any, err error) {
+		if err != nil {
+			t.Fatalf("error listing %s: %v", name, err)
+		}
+		fmt.Fprintf(&out, "%s: %+v\n", name, rows)
+	}
+	users, err := s.ListUsers(ctx)
+	dump("users", users, err)
+	feeds, err := s.ListFeeds(ctx)
+	dump("feeds", feeds, err)
+	folders, err := s.ListFolders(ctx)
+	dump("folders", folders, err)
+	follows, err := s.ListFeedFollows(ctx)
+	dump("follows", follows, err)
+	history, err := s.ListFeedURLHistory(ctx)
+	dump("url history", history, err)
+	posts, err := s.ListPosts(ctx)
+	dump("posts", posts, err)
+	states, err := s.ListPostStates(ctx)
+	dump("post states", states, err)
+	archived, err := s.ListArchivedPosts(ctx)
+	dump("archived posts", archived, err)
+	pruned, err := s.ListPrunedPosts(ctx)
+	dump("pruned posts", pruned, err)
+	return out.String()
+}
+
+// Alice and Bob both follow Blog, which alice added, and Bob added and follows News.
+// Each feed has a post Bob has starred.
+func seedRollbackTest(t *testing.T, s *state) (alice, bob database.User) {
+	t.Helper()
+	alice = dbtest.CreateUser(t, s.db, "alice", true)
+	bob = dbtest.CreateUser(t, s.db, "bob", false)
+	blog, err := addFeed(s, alice, "Blog", "https://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	news, err := addFeed(s, bob, "News", "https://news.example/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := followFeed(s, bob, blog.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, feed := range []database.Feed{blog, news} {
+		post := dbtest.CreatePost(t, s.db, feed, dbtest.Post{Title: "Hello", Day: 1})
+		err := s.db.SetPostStarred(context.Background(), database.SetPostStarredParams{
+			UserID:    bob.ID,
+			PostID:    post.ID,
+			Starred:   true,
+			UpdatedAt: blog.CreatedAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return alice, bob
+}
+
+func TestFailuresLeaveNothingBehind(t *testing.T) {
+	opml := filepath.Join(t.TempDir(), "subscriptions.opml")
+	err := os.WriteFile(opml, []byte(`<opml version="2.0"><body>
+<outline text="Reading">
+  <outline text="First" xmlUrl="https://first.example/feed"/>
+  <outline text="Second" xmlUrl="https://second.example/feed"/>
+  <outline text="Blog" xmlUrl="https://example.com/feed"/>
+</outline>
+</body></opml>`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		user    string
+		method  string
+		calls   int
+		command []string
+		wantErr bool
+		// Feeds the user follows afterwards, for commands that carry on past a failure
+		follows []string
+	}{
+		{"addfeed without the follow", "bob", "CreateFeedFollow", 0, []string{"addfeed", "Orphan", "https://orphan.example/feed"}, true, nil},
+		{"merge without the posts", "alice", "MoveFeedPosts", 0, []string{"feed", "merge", "Blog", "News"}, true, nil},
+		{"merge without deleting the old feed", "alice", "DeleteFeed", 0, []string{"feed", "merge", "Blog", "News"}, true, nil},
+		{"merge without the url history", "alice", "AddFeedURLHistory", 0, []string{"feed", "merge", "Blog", "News"}, true, nil},
+		{"import without the second follow", "bob", "CreateFeedFollow", 1, []string{"import", opml}, false, []string{"Blog", "First", "News"}},
+		{"reset of a user", "alice", "DeleteUser", 0, []string{"reset", "--user", "bob", "--yes"}, true, nil},
+		{"reset of everything", "alice", "ResetUsers", 0, []string{"reset", "--yes"}, true, nil},
+	}
+
+	for _, backend := range dbtest.Backends(t) {
+		for _, tt := range tests {
+			t.Run(backend.Name+"/"+tt.name, func(t *testing.T) {
+				// reset saves a snapshot in the home directory first
+				t.Setenv("HOME", t.TempDir())
+
+				s := &state{db: backend.Open(t), config: &config.Config{CurrentUserName: tt.user}, out: io.Discard}
+				_, bob := seedRollbackTest(t, s)
+				before := dumpStore(t, s.db)
+
+				failing := &state{db: failAfter(s.db, tt.method, tt.calls), config: s.config, out: io.Discard}
+				_, err := runTestCommand(failing, tt.command[0], tt.command[1:]...)
+				if tt.wantErr != errors.Is(err, errInjected) {
+					t.Fatalf("%q error = %v, want the injected failure: %v", tt.command, err, tt.wantErr)
+				}
+
+				if tt.follows != nil {
+					got := followedFeeds(t, s, bob)
+					if strings.Join(got, "|") != strings.Join(tt.follows, "|") {
+						t.Errorf("bob follows %q, want %q", got, tt.follows)
+					}
+					if feed, err := lookupFeed(s, "https://second.example/feed"); err == nil {
+						t.Errorf("the feed whose follow failed was left behind: %+v", feed)
+					}
+					return
+				}
+				if after := dumpStore(t, s.db); after != before {
+					t.Errorf("the failed %q changed the database\nbefore:\n%s\nafter:\n%s", tt.command, before, after)
+				}
+			})
+		}
+	}
+}
+
+func TestFailingStoreFailsInTransactions(t *testing.T) {
+	s := failAfter(database.NewMemoryStore(), "DeleteFeed", 1)
+	calls := 0
+	err := s.InTx(context.Background(), func(tx database.Store) error {
+		for range 2 {
+			if err := tx.DeleteFeed(context.Background(), uuid.New()); err != nil {
+				return err
+			}
+			calls++
+		}
+		return nil
+	})
+	if !errors.Is(err, errInjected) || calls != 1 {
+		t.Errorf("InTx = %v after %d calls, want the injected failure on the second", err, calls)
+	}
+	if *s.remaining != 0 {
+		t.Errorf("remaining calls = %d, want 0", *s.remaining)
+	}
+}
+
+func TestResetUserKeepsTheirFeeds(t *testing.T) {
+	for _, backend := range dbtest.Backends(t) {
+		t.Run(backend.Name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			s := &state{db: backend.Open(t), config: &config.Config{CurrentUserName: "alice"}, out: io.Discard}
+			alice, bob := seedRollbackTest(t, s)
+			carol := dbtest.CreateUser(t, s.db, "carol", false)
+			news, err := lookupFeed(s, "News")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := followFeed(s, carol, news.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := runTestCommand(s, "reset", "--user", "bob", "--yes"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.db.GetUser(context.Background(), bob.Name); err == nil {
+				t.Error("bob is still there")
+			}
+			news, err = lookupFeed(s, "News")
+			if err != nil {
+				t.Fatalf("bob's feed went with him: %v", err)
+			}
+			if news.UserID != alice.ID {
+				t.Errorf("News belongs to %s, want alice", news.UserID)
+			}
+			if got := followedFeeds(t, s, carol); len(got) != 1 || got[0] != "News" {
+				t.Errorf("carol follows %q, want News", got)
+			}
+			posts, err := s.db.ListPosts(context.Background())
+			if err != nil || len(posts) != 2 {
+				t.Errorf("posts left = %d, %v, want both", len(posts), err)
+			}
+			states, err := s.db.ListPostStates(context.Background())
+			if err != nil || len(states) != 0 {
+				t.Errorf("bob's stars left = %+v, %v", states, err)
+			}
+		})
+	}
+}
+
+func TestMergeMovesEverything(t *testing.T) {
+	for _, backend := range dbtest.Backends(t) {
+		t.Run(backend.Name, func(t *testing.T) {
+			ctx := context.Background()
+			s := &state{db: backend.Open(t), config: &config.Config{CurrentUserName: "alice"}, out: io.Discard}
+			_, bob := seedRollbackTest(t, s)
+			blog, err := lookupFeed(s, "Blog")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Prune Blog's post, keeping a copy in the archive
+			posts, err := s.db.ListPosts(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, post := range posts {
+				if post.FeedID != blog.ID {
+					continue
+				}
+				if err := s.db.ArchivePost(ctx, database.ArchivePostParams{ID: post.ID, ArchivedAt: post.CreatedAt}); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.db.AddPrunedPost(ctx, database.AddPrunedPostParams{ID: post.ID, PrunedAt: post.CreatedAt}); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.db.DeletePost(ctx, post.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := runTestCommand(s, "feed", "merge", "Blog", "News"); err != nil {
+				t.Fatal(err)
+			}
+
+			news, err := lookupFeed(s, "https://example.com/feed")
+			if err != nil || news.Name != "News" {
+				t.Fatalf("Blog's url finds %+v, %v, want News", news, err)
+			}
+			archived, err := s.db.ListArchivedPosts(ctx)
+			if err != nil || len(archived) != 1 || archived[0].FeedID != news.ID {
+				t.Errorf("archived posts = %+v, %v, want Blog's post under News", archived, err)
+			}
+			pruned, err := s.db.ListPrunedPosts(ctx)
+			if err != nil || len(pruned) != 1 || pruned[0].FeedID != news.ID {
+				t.Errorf("pruned posts = %+v, %v, want Blog's post under News", pruned, err)
+			}
+			if isPruned, err := s.db.IsPostPruned(ctx, "https://example.com/feed/hello"); err != nil || !isPruned {
+				t.Errorf("IsPostPruned(Blog's post) = %v, %v, want true so fetching News doesn't bring it back", isPruned, err)
+			}
+			if got := followedFeeds(t, s, bob); len(got) != 1 || got[0] != "News" {
+				t.Errorf("bob follows %q, want only News", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/database/dbtest"
+)
+
+// Serves the feeds in testdata, along with a feed that's gone, one that moved and one that's broken
+func newFeedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("GET /feeds/", http.StripPrefix("/feeds/", http.FileServer(http.Dir("testdata"))))
+	mux.HandleFunc("GET /gone.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	mux.HandleFunc("GET /moved.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feeds/rss.xml", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("GET /elsewhere.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feeds/rss.xml", http.StatusFound)
+	})
+	mux.HandleFunc("GET /broken.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Adds a feed for user and fetches it once
+func scrapeTestFeed(t *testing.T, s *state, user database.User, feedURL string) (database.Feed, error) {
+	t.Helper()
+	feed, err := addFeed(s, user, "Test feed", feedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed, scrapeFeed(s, database.GetNextFeedToFetchRow{ID: feed.ID, Url: feed.Url, Name: feed.Name})
+}
+
+func testPosts(t *testing.T, s *state, user database.User) map[string]database.GetPostsForUserRow {
+	t.Helper()
+	rows, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{UserID: user.ID, MaxPosts: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts := map[string]database.GetPostsForUserRow{}
+	for _, row := range rows {
+		posts[row.Url] = row
+	}
+	return posts
+}
+
+func TestScrapeRSS(t *testing.T) {
+	server := newFeedServer(t)
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	feed, err := scrapeTestFeed(t, s, alice, server.URL+"/feeds/rss.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := testPosts(t, s, alice)
+	if len(posts) != 3 {
+		t.Fatalf("got %d posts, want 3 (the undated one is skipped)", len(posts))
+	}
+
+	generics := posts["https://blog.example.com/generics"]
+	if generics.Title != "Generics & you" || generics.PublishedAt != "2026-10-05T10:00:00Z" {
+		t.Errorf("generics post = %q published %q", generics.Title, generics.PublishedAt)
+	}
+	if generics.Description != "Type parameters **explained**." {
+		t.Errorf("generics description = %q, want the markdown without the tracking pixel", generics.Description)
+	}
+	if strings.Join(generics.AuthorNames(), "|") != "Jane Doe" || strings.Join(generics.CategoryNames(), "|") != "Go|Language" {
+		t.Errorf("generics authors %q, categories %q", generics.AuthorNames(), generics.CategoryNames())
+	}
+
+	whole := posts["https://blog.example.com/whole"]
+	if whole.Description != "Just a teaser" || !strings.Contains(whole.Content, "[relative link](https://blog.example.com/more)") {
+		t.Errorf("whole post description %q, content %q", whole.Description, whole.Content)
+	}
+	if authors := whole.AuthorNames(); len(authors) != 1 || authors[0] != "rob@example.com (Rob Pike)" {
+		t.Errorf("whole post authors = %q", authors)
+	}
+
+	trick := posts["https://blog.example.com/trick"]
+	if strings.ContainsAny(trick.Title, "\x1b\x07") || trick.Title != "Terminal ]52;c;ZXZpbA== trick" {
+		t.Errorf("trick title = %q, want the control characters dropped", trick.Title)
+	}
+
+	// Fetching again doesn't add the same posts twice
+	err = scrapeFeed(s, database.GetNextFeedToFetchRow{ID: feed.ID, Url: feed.Url, Name: feed.Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := testPosts(t, s, alice); len(again) != 3 {
+		t.Errorf("got %d posts after fetching twice, want 3", len(again))
+	}
+}
+
+func TestScrapeAtom(t *testing.T) {
+	server := newFeedServer(t)
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	if _, err := scrapeTestFeed(t, s, alice, server.URL+"/feeds/atom.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := testPosts(t, s, alice)
+	entry, ok := posts["https://atom.example.com/entry"]
+	if !ok || len(posts) != 1 {
+		t.Fatalf("posts = %+v, want the one entry at its alternate link", posts)
+	}
+	if entry.Title != "Atom & entries" || entry.PublishedAt != "2026-10-08T10:00:00Z" {
+		t.Errorf("entry = %q published %q", entry.Title, entry.PublishedAt)
+	}
+	if entry.Description != "A summary" || !strings.Contains(entry.Content, "Inline *xhtml* content") {
+		t.Errorf("entry description %q, content %q", entry.Description, entry.Content)
+	}
+	if authors := entry.AuthorNames(); len(authors) != 1 || authors[0] != "Doe, Jane" || entry.Categories != "atom" {
+		t.Errorf("entry authors %q, categories %q", authors, entry.CategoryNames())
+	}
+}
+
+func TestScrapeSkipsPrunedPosts(t *testing.T) {
+	server := newFeedServer(t)
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	feed, err := scrapeTestFeed(t, s, alice, server.URL+"/feeds/rss.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruned := testPosts(t, s, alice)["https://blog.example.com/generics"]
+	if err := s.db.AddPrunedPost(context.Background(), database.AddPrunedPostParams{ID: pruned.ID, PrunedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.DeletePost(context.Background(), pruned.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	err = scrapeFeed(s, database.GetNextFeedToFetchRow{ID: feed.ID, Url: feed.Url, Name: feed.Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts := testPosts(t, s, alice)
+	if _, back := posts[pruned.Url]; back || len(posts) != 2 {
+		t.Errorf("posts after pruning and fetching again = %d, pruned post back: %v", len(posts), back)
+	}
+}
+
+func TestScrapeFeedStatus(t *testing.T) {
+	server := newFeedServer(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+		active  bool
+		url     string
+		posts   int
+	}{
+		{"gone", "/gone.xml", "", false, "/gone.xml", 0},
+		{"moved for good", "/moved.xml", "", true, "/feeds/rss.xml", 3},
+		{"moved for now", "/elsewhere.xml", "", true, "/elsewhere.xml", 3},
+		{"server error", "/broken.xml", "500 Internal Server Error", true, "/broken.xml", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestState(t)
+			alice := dbtest.CreateUser(t, s.db, "alice", false)
+			feed, err := scrapeTestFeed(t, s, alice, server.URL+tt.path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("scrapeFeed error = %v, want %q", err, tt.wantErr)
+			}
+
+			feed, err = s.db.GetFeedByID(context.Background(), feed.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feed.Active != tt.active || feed.Url != server.URL+tt.url {
+				t.Errorf("feed is at %s, active %v, want %s, active %v", feed.Url, feed.Active, server.URL+tt.url, tt.active)
+			}
+			if posts := testPosts(t, s, alice); len(posts) != tt.posts {
+				t.Errorf("got %d posts, want %d", len(posts), tt.posts)
+			}
+
+			// A feed that moved can still be found by its old url
+			if found, err := lookupFeed(s, server.URL+tt.path); err != nil || found.ID != feed.ID {
+				t.Errorf("lookupFeed(old url) = %+v, %v", found, err)
+			}
+		})
+	}
+}
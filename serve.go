@@ -0,0 +1,483 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/api"
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Posts returned by GET /api/posts when the request doesn't ask for a number, and the most it can ask for
+const (
+	defaultAPIPosts = 20
+	maxAPIPosts     = 500
+)
+
+func handlerServe(s *state, cmd command) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on, e.g. :8080 for every interface")
+	args := parseFlags(fs, cmd.arguments)
+
+	if len(args) != 0 {
+		return fmt.Errorf("usage: serve [--addr host:port]")
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           serveRoutes(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Finish the requests in flight on ctrl-c rather than cutting them off
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(s.out, "Serving gator on http://%s, the API spec is at /api/openapi.yaml\n", *addr)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// A request handler for a user who has been let in with an API token
+type apiHandler func(s *state, w http.ResponseWriter, r *http.Request, user database.User) error
+
+// An error with the HTTP status it should be reported with
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func apiErrorf(status int, format string, args ...any) error {
+	return &apiError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// The API under /api and the web reader everywhere else
+func serveRoutes(s *state) http.Handler {
+	mux := http.NewServeMux()
+	addAPIRoutes(s, mux)
+	addWebRoutes(s, mux)
+	return mux
+}
+
+func addAPIRoutes(s *state, mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(api.Spec)
+	})
+
+	handle := func(pattern string, handler apiHandler) {
+		mux.Handle(pattern, apiAuth(s, handler))
+	}
+	handle("GET /api/me", apiGetMe)
+	handle("GET /api/users", apiGetUsers)
+	handle("GET /api/feeds", apiGetFeeds)
+	handle("POST /api/feeds", apiAddFeed)
+	handle("GET /api/follows", apiGetFollows)
+	handle("POST /api/follows", apiFollowFeed)
+	handle("DELETE /api/follows/{feed}", apiUnfollow)
+	handle("GET /api/posts", apiGetPosts)
+	handle("PUT /api/posts/{post}/read", apiSetPostState(true, false))
+	handle("DELETE /api/posts/{post}/read", apiSetPostState(false, false))
+	handle("PUT /api/posts/{post}/starred", apiSetPostState(true, true))
+	handle("DELETE /api/posts/{post}/starred", apiSetPostState(false, true))
+
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, apiErrorf(http.StatusNotFound, "no such endpoint, see /api/openapi.yaml"))
+	})
+}
+
+// Works out the user from the request's bearer token, then runs handler for them
+func apiAuth(s *state, handler apiHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = ""
+		}
+		user, err := userForToken(s, r, strings.TrimSpace(token))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, err)
+			return
+		}
+
+		err = handler(s, w, r, user)
+		if err != nil {
+			writeAPIError(w, err)
+		}
+	})
+}
+
+// Returns the user a token belongs to
+func userForToken(s *state, r *http.Request, token string) (database.User, error) {
+	if token == "" {
+		return database.User{}, apiErrorf(http.StatusUnauthorized, "missing token, create one with: gator token create <name>")
+	}
+	user, err := s.db.GetUserByAPIToken(r.Context(), hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, apiErrorf(http.StatusUnauthorized, "unknown or revoked token")
+	}
+	return user, err
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(body)
+}
+
+// The status to report err with and the message the client sees. Errors the client can do
+// something about are passed on, anything else is logged and only reported as an internal error.
+func errorResponse(err error) (int, string) {
+	var apiErr *apiError
+	var lookupErr *lookupError
+	var ambiguousErr *ambiguousError
+	var existsErr *feedExistsError
+	var invalidErr *invalidFeedError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.status, apiErr.message
+	case errors.As(err, &lookupErr):
+		return http.StatusNotFound, lookupErr.message
+	case errors.As(err, &ambiguousErr):
+		return http.StatusBadRequest, ambiguousErr.Error()
+	case errors.As(err, &invalidErr):
+		return http.StatusBadRequest, invalidErr.message
+	case errors.As(err, &existsErr):
+		return http.StatusConflict, fmt.Sprintf("%s is already in gator as %s", existsErr.url, existsErr.name)
+	case database.IsUniqueViolation(err):
+		return http.StatusConflict, "already exists"
+	}
+	log.Println("serve:", err)
+	return http.StatusInternalServerError, "internal error"
+}
+
+func writeAPIError(w http.ResponseWriter, err error) {
+	status, message := errorResponse(err)
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err != nil {
+		return apiErrorf(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// What the API returns, kept apart from the database rows so the JSON stays the same when they change
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	IsAdmin   bool      `json:"is_admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiFeed struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	AddedBy       uuid.UUID  `json:"added_by"`
+	Active        bool       `json:"active"`
+	FetchFullText bool       `json:"fetch_full_text"`
+	LastFetchedAt time.Time  `json:"last_fetched_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+type apiFollow struct {
+	FeedID   uuid.UUID `json:"feed_id"`
+	FeedName string    `json:"feed_name"`
+	FeedUrl  string    `json:"feed_url"`
+	Title    string    `json:"title,omitempty"`
+	Folder   string    `json:"folder,omitempty"`
+	Active   bool      `json:"active"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	Content     string    `json:"content,omitempty"`
+	PublishedAt string    `json:"published_at"`
+	FeedTitle   string    `json:"feed_title"`
+	Authors     []string  `json:"authors"`
+	Categories  []string  `json:"categories"`
+	Read        bool      `json:"read"`
+	Starred     bool      `json:"starred"`
+}
+
+type apiPosts struct {
+	Posts []apiPost `json:"posts"`
+	// Pass as before to get the next page, missing on the last page
+	Next string `json:"next,omitempty"`
+}
+
+func newAPIUser(user database.User) apiUser {
+	return apiUser{ID: user.ID, Name: user.Name, IsAdmin: user.IsAdmin, CreatedAt: user.CreatedAt}
+}
+
+func newAPIFeed(feed database.Feed) apiFeed {
+	f := apiFeed{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		Url:           feed.Url,
+		AddedBy:       feed.UserID,
+		Active:        feed.Active,
+		FetchFullText: feed.FetchFullText,
+		LastFetchedAt: feed.LastFetchedAt,
+		CreatedAt:     feed.CreatedAt,
+	}
+	if feed.DeactivatedAt.Valid {
+		f.DeactivatedAt = &feed.DeactivatedAt.Time
+	}
+	return f
+}
+
+func apiGetMe(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	writeJSON(w, http.StatusOK, newAPIUser(user))
+	return nil
+}
+
+func apiGetUsers(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	users, err := s.db.ListUsers(r.Context())
+	if err != nil {
+		return err
+	}
+	items := []apiUser{}
+	for _, u := range users {
+		items = append(items, newAPIUser(u))
+	}
+	writeJSON(w, http.StatusOK, items)
+	return nil
+}
+
+func apiGetFeeds(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	feeds, err := s.db.ListFeeds(r.Context())
+	if err != nil {
+		return err
+	}
+	items := []apiFeed{}
+	for _, feed := range feeds {
+		items = append(items, newAPIFeed(feed))
+	}
+	writeJSON(w, http.StatusOK, items)
+	return nil
+}
+
+func apiAddFeed(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	var body struct {
+		Name string `json:"name"`
+		Url  string `json:"url"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		return err
+	}
+	feed, err := addFeed(s, user, body.Name, body.Url)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, newAPIFeed(feed))
+	return nil
+}
+
+func apiGetFollows(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	items := []apiFollow{}
+	for _, f := range follows {
+		items = append(items, apiFollow{
+			FeedID:   f.FeedID,
+			FeedName: f.FeedName,
+			FeedUrl:  f.FeedUrl,
+			Title:    f.Title,
+			Folder:   f.FolderName,
+			Active:   f.FeedActive,
+		})
+	}
+	writeJSON(w, http.StatusOK, items)
+	return nil
+}
+
+func apiFollowFeed(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	var body struct {
+		Feed string `json:"feed"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		return err
+	}
+	if body.Feed == "" {
+		return apiErrorf(http.StatusBadRequest, "feed is required")
+	}
+
+	feed, err := lookupFeed(s, body.Feed)
+	if err != nil {
+		return err
+	}
+	err = followFeed(s, user, feed.ID)
+	if database.IsUniqueViolation(err) {
+		return apiErrorf(http.StatusConflict, "%s is already following %s", user.Name, feed.Name)
+	}
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, newAPIFeed(feed))
+	return nil
+}
+
+func apiUnfollow(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	feed, err := lookupFeed(s, r.PathValue("feed"))
+	if err != nil {
+		return err
+	}
+	deleted, err := s.db.DeleteFollow(r.Context(), database.DeleteFollowParams{Name: user.Name, Url: feed.Url})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return apiErrorf(http.StatusNotFound, "%s isn't following %s", user.Name, feed.Name)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func apiGetPosts(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+	query := r.URL.Query()
+
+	limit := defaultAPIPosts
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAPIPosts {
+			return apiErrorf(http.StatusBadRequest, "limit must be a number from 1 to %d", maxAPIPosts)
+		}
+	}
+
+	starred := false
+	if value := query.Get("starred"); value != "" {
+		var err error
+		starred, err = strconv.ParseBool(value)
+		if err != nil {
+			return apiErrorf(http.StatusBadRequest, "starred must be true or false")
+		}
+	}
+
+	arg := database.GetPostsForUserParams{
+		UserID:      user.ID,
+		Folder:      query.Get("folder"),
+		Author:      query.Get("author"),
+		Category:    query.Get("category"),
+		StarredOnly: starred,
+		MaxPosts:    int32(limit),
+	}
+	if before := query.Get("before"); before != "" {
+		post, err := apiLookupPost(s, user, before)
+		if err != nil {
+			return err
+		}
+		arg.BeforePublishedAt = sql.NullString{String: post.PublishedAt, Valid: true}
+		arg.BeforeID = uuid.NullUUID{UUID: post.ID, Valid: true}
+	}
+
+	posts, err := s.db.GetPostsForUser(r.Context(), arg)
+	if err != nil {
+		return err
+	}
+
+	page := apiPosts{Posts: []apiPost{}}
+	for _, p := range posts {
+		page.Posts = append(page.Posts, apiPost{
+			ID:          p.ID,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			Content:     p.Content,
+			PublishedAt: p.PublishedAt,
+			FeedTitle:   p.FeedTitle,
+			Authors:     p.AuthorNames(),
+			Categories:  p.CategoryNames(),
+			Read:        p.Read,
+			Starred:     p.Starred,
+		})
+	}
+	if len(posts) == limit {
+		page.Next = posts[len(posts)-1].ID.String()
+	}
+	writeJSON(w, http.StatusOK, page)
+	return nil
+}
+
+// Finds one of the user's posts by its full id. The short ids and numbers browse shows aren't
+// taken, since which post they mean changes as posts come in.
+func apiLookupPost(s *state, user database.User, ref string) (database.GetPostsByIDPrefixRow, error) {
+	id, err := uuid.Parse(ref)
+	if err != nil {
+		return database.GetPostsByIDPrefixRow{}, apiErrorf(http.StatusBadRequest, "%s isn't a post id", ref)
+	}
+	return lookupPost(s, user, id.String())
+}
+
+// Sets or clears the read flag, or the starred flag, on a post
+func apiSetPostState(set, starred bool) apiHandler {
+	return func(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+		post, err := apiLookupPost(s, user, r.PathValue("post"))
+		if err != nil {
+			return err
+		}
+
+		if starred {
+			err = s.db.SetPostStarred(r.Context(), database.SetPostStarredParams{
+				UserID:    user.ID,
+				PostID:    post.ID,
+				Starred:   set,
+				UpdatedAt: time.Now(),
+			})
+		} else {
+			err = s.db.SetPostRead(r.Context(), database.SetPostReadParams{
+				UserID:    user.ID,
+				PostID:    post.ID,
+				Read:      set,
+				UpdatedAt: time.Now(),
+			})
+		}
+		if err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/database/dbtest"
+	"github.com/google/uuid"
+)
+
+const testToken = "test-token"
+
+// A server on the memory store where alice, who has testToken, follows Blog in her Tech folder and
+// bob's News, with two posts on Blog and one on News
+func newAPITestServer(t *testing.T) (http.Handler, map[string]database.Post) {
+	t.Helper()
+	s, posts := newAPITestState(t)
+	return serveRoutes(s), posts
+}
+
+// The state behind newAPITestServer
+func newAPITestState(t *testing.T) (*state, map[string]database.Post) {
+	t.Helper()
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := dbtest.CreateUser(t, s.db, "alice", false)
+	bob := dbtest.CreateUser(t, s.db, "bob", false)
+	_, err := s.db.CreateAPIToken(ctx, database.CreateAPITokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    alice.ID,
+		Name:      "test",
+		TokenHash: hashToken(testToken),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blog, err := addFeed(s, alice, "Blog", "https://blog.example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	news, err := addFeed(s, bob, "News", "https://news.example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := followFeed(s, alice, news.ID); err != nil {
+		t.Fatal(err)
+	}
+	folder, err := s.db.CreateFolder(ctx, database.CreateFolderParams{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), UserID: alice.ID, Name: "Tech"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.SetFollowFolder(ctx, database.SetFollowFolderParams{
+		UserID:    alice.ID,
+		FeedID:    blog.ID,
+		FolderID:  uuid.NullUUID{UUID: folder.ID, Valid: true},
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := map[string]database.Post{
+		"Generics": dbtest.CreatePost(t, s.db, blog, dbtest.Post{Title: "Generics", Day: 5, Authors: []string{"Doe, Jane"}, Categories: []string{"Go", "Language"}}),
+		"Errors":   dbtest.CreatePost(t, s.db, blog, dbtest.Post{Title: "Errors", Day: 4, Authors: []string{"Rob"}, Categories: []string{"Go"}}),
+		"Election": dbtest.CreatePost(t, s.db, news, dbtest.Post{Title: "Election", Day: 3, Categories: []string{"Politics"}}),
+	}
+	return s, posts
+}
+
+// Sends a request to handler with token as the bearer token, leaving the header off when it's empty
+func apiRequest(t *testing.T, handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func getTestPosts(t *testing.T, handler http.Handler, query string) apiPosts {
+	t.Helper()
+	w := apiRequest(t, handler, "GET", "/api/posts?"+query, testToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/posts?%s = %d %s", query, w.Code, w.Body)
+	}
+	var page apiPosts
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func pageTitles(page apiPosts) string {
+	titles := []string{}
+	for _, post := range page.Posts {
+		titles = append(titles, post.Title)
+	}
+	return strings.Join(titles, "|")
+}
+
+func TestAPIAuth(t *testing.T) {
+	handler, _ := newAPITestServer(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"no token", "/api/me", "", http.StatusUnauthorized},
+		{"empty token", "/api/me", "Bearer ", http.StatusUnauthorized},
+		{"unknown token", "/api/me", "Bearer wrong", http.StatusUnauthorized},
+		{"not a bearer token", "/api/me", "Basic " + testToken, http.StatusUnauthorized},
+		{"token", "/api/me", "Bearer " + testToken, http.StatusOK},
+		{"spec without a token", "/api/openapi.yaml", "", http.StatusOK},
+		{"unknown endpoint", "/api/nope", "Bearer " + testToken, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("GET %s = %d %s, want %d", tt.path, w.Code, w.Body, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	var me apiUser
+	if err := json.Unmarshal(apiRequest(t, handler, "GET", "/api/me", testToken, "").Body.Bytes(), &me); err != nil || me.Name != "alice" {
+		t.Errorf("GET /api/me = %+v, %v, want alice", me, err)
+	}
+}
+
+func TestAPIPosts(t *testing.T) {
+	handler, _ := newAPITestServer(t)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "Generics|Errors|Election"},
+		{"author=" + url.QueryEscape("doe, j"), "Generics"},
+		{"category=go", "Generics|Errors"},
+		{"folder=Tech", "Generics|Errors"},
+		{"folder=Tech&author=rob", "Errors"},
+		{"category=Sport", ""},
+	}
+	for _, tt := range tests {
+		if got := pageTitles(getTestPosts(t, handler, tt.query)); got != tt.want {
+			t.Errorf("GET /api/posts?%s = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	generics := getTestPosts(t, handler, "author=Jane").Posts[0]
+	if strings.Join(generics.Authors, "|") != "Doe, Jane" || strings.Join(generics.Categories, "|") != "Go|Language" {
+		t.Errorf("authors %q, categories %q, want the comma kept in the one author", generics.Authors, generics.Categories)
+	}
+
+	// Paging through with next
+	first := getTestPosts(t, handler, "limit=2")
+	if pageTitles(first) != "Generics|Errors" || first.Next == "" {
+		t.Fatalf("first page = %q, next %q", pageTitles(first), first.Next)
+	}
+	second := getTestPosts(t, handler, "limit=2&before="+first.Next)
+	if pageTitles(second) != "Election" || second.Next != "" {
+		t.Errorf("second page = %q, next %q, want Election and no next", pageTitles(second), second.Next)
+	}
+
+	for _, query := range []string{"limit=0", "limit=many", "starred=maybe"} {
+		if w := apiRequest(t, handler, "GET", "/api/posts?"+query, testToken, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET /api/posts?%s = %d, want 400", query, w.Code)
+		}
+	}
+	if w := apiRequest(t, handler, "GET", "/api/posts?before="+uuid.NewString(), testToken, ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /api/posts with an unknown before = %d, want 404", w.Code)
+	}
+	if w := apiRequest(t, handler, "GET", "/api/posts?before="+first.Next[:8], testToken, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("GET /api/posts with a short id as before = %d, want 400", w.Code)
+	}
+}
+
+func TestAPIPostState(t *testing.T) {
+	handler, posts := newAPITestServer(t)
+	id := posts["Errors"].ID.String()
+
+	postState := func() (read, starred bool) {
+		t.Helper()
+		for _, post := range getTestPosts(t, handler, "").Posts {
+			if post.Title == "Errors" {
+				return post.Read, post.Starred
+			}
+		}
+		t.Fatal("Errors is missing")
+		return false, false
+	}
+
+	steps := []struct {
+		method  string
+		flag    string
+		read    bool
+		starred bool
+	}{
+		{"PUT", "read", true, false},
+		{"PUT", "starred", true, true},
+		{"DELETE", "read", false, true},
+		{"DELETE", "starred", false, false},
+	}
+	for _, step := range steps {
+		w := apiRequest(t, handler, step.method, "/api/posts/"+id+"/"+step.flag, testToken, "")
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("%s %s = %d %s", step.method, step.flag, w.Code, w.Body)
+		}
+		if read, starred := postState(); read != step.read || starred != step.starred {
+			t.Errorf("after %s %s read %v, starred %v, want %v, %v", step.method, step.flag, read, starred, step.read, step.starred)
+		}
+	}
+
+	apiRequest(t, handler, "PUT", "/api/posts/"+id+"/starred", testToken, "")
+	if got := pageTitles(getTestPosts(t, handler, "starred=true")); got != "Errors" {
+		t.Errorf("starred posts = %q, want Errors", got)
+	}
+
+	if w := apiRequest(t, handler, "PUT", "/api/posts/"+uuid.NewString()+"/read", testToken, ""); w.Code != http.StatusNotFound {
+		t.Errorf("PUT read on an unknown post = %d, want 404", w.Code)
+	}
+	// Short ids and browse numbers change meaning as posts come in
+	for _, ref := range []string{id[:8], "1"} {
+		if w := apiRequest(t, handler, "PUT", "/api/posts/"+ref+"/read", testToken, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("PUT read on post %s = %d, want 400", ref, w.Code)
+		}
+	}
+}
+
+func TestAPIFollows(t *testing.T) {
+	handler, _ := newAPITestServer(t)
+
+	steps := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"DELETE", "/api/follows/News", "", http.StatusNoContent},
+		{"DELETE", "/api/follows/News", "", http.StatusNotFound},
+		{"POST", "/api/follows", `{"feed":"News"}`, http.StatusCreated},
+		{"POST", "/api/follows", `{"feed":"news"}`, http.StatusConflict},
+		{"POST", "/api/follows", `{"feed":"Nothing"}`, http.StatusNotFound},
+		{"POST", "/api/follows", `{}`, http.StatusBadRequest},
+		{"POST", "/api/follows", `{"feed":"News","extra":1}`, http.StatusBadRequest},
+		{"POST", "/api/feeds", `{"name":"Copy","url":"https://blog.example.com/feed"}`, http.StatusConflict},
+		{"POST", "/api/feeds", `{"name":"Bad","url":"javascript:alert(1)"}`, http.StatusBadRequest},
+		{"POST", "/api/feeds", `{"name":"news","url":"https://other.example.com/news"}`, http.StatusCreated},
+		{"POST", "/api/follows", `{"feed":"News"}`, http.StatusBadRequest},
+		{"DELETE", "/api/follows/News", "", http.StatusBadRequest},
+	}
+	for _, step := range steps {
+		w := apiRequest(t, handler, step.method, step.path, testToken, step.body)
+		if w.Code != step.want {
+			t.Errorf("%s %s %s = %d %s, want %d", step.method, step.path, step.body, w.Code, w.Body, step.want)
+		}
+	}
+
+	var follows []apiFollow
+	w := apiRequest(t, handler, "GET", "/api/follows", testToken, "")
+	if err := json.Unmarshal(w.Body.Bytes(), &follows); err != nil || len(follows) != 3 {
+		t.Errorf("GET /api/follows = %s, want Blog and both News feeds", w.Body)
+	}
+}
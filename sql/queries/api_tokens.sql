@@ -0,0 +1,29 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetUserByAPIToken :one
+SELECT users.* FROM users
+INNER JOIN api_tokens
+ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = $1;
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at, name;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetAPIToken :one
+SELECT * FROM api_tokens
+WHERE token_hash = $1;
@@ -0,0 +1,67 @@
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY created_at, id;
+
+-- name: ListFeeds :many
+SELECT * FROM feeds
+ORDER BY created_at, id;
+
+-- name: ListFolders :many
+SELECT * FROM folders
+ORDER BY created_at, id;
+
+-- name: ListFeedFollows :many
+SELECT * FROM feed_follows
+ORDER BY created_at, id;
+
+-- name: ListFeedURLHistory :many
+SELECT * FROM feed_url_history
+ORDER BY moved_at, url;
+
+-- name: ListPosts :many
+SELECT * FROM posts
+ORDER BY created_at, id;
+
+-- name: ListPostAuthors :many
+SELECT * FROM post_authors
+ORDER BY post_id;
+
+-- name: ListPostCategories :many
+SELECT * FROM post_categories
+ORDER BY post_id;
+
+-- name: ListPostStates :many
+SELECT * FROM post_states
+ORDER BY user_id, post_id;
+
+-- name: ListArchivedPosts :many
+SELECT * FROM archived_posts
+ORDER BY archived_at, id;
+
+-- name: ListPrunedPosts :many
+SELECT * FROM pruned_posts
+ORDER BY pruned_at, url;
+
+-- name: ListAPITokens :many
+SELECT * FROM api_tokens
+ORDER BY created_at, id;
+
+-- name: RestoreFeed :exec
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, active, deactivated_at, retention_days, retention_posts)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: RestoreFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id, title, notified_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: RestorePostState :exec
+INSERT INTO post_states (user_id, post_id, read, starred, updated_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: RestoreArchivedPost :exec
+INSERT INTO archived_posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, archived_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: RestorePrunedPost :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+VALUES ($1, $2, $3);
@@ -1,32 +1,43 @@
 -- name: CreateFeedFollow :one
-WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
-    VALUES (
-        $1,
-        $2,
-        $3,
-        $4,
-        $5
-    )
-    RETURNING *)
-
-SELECT inserted_feed_follow.*, users.name AS user_name, feeds.name AS feed_name
-FROM inserted_feed_follow
-INNER JOIN feeds
-ON inserted_feed_follow.feed_id = feeds.id
-INNER JOIN users
-ON inserted_feed_follow.user_id = users.id;
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, notified_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $2
+)
+RETURNING *,
+    (SELECT users.name FROM users WHERE users.id = feed_follows.user_id) AS user_name,
+    (SELECT feeds.name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.*, users.name AS user_name, COALESCE(NULLIF(feed_follows.title, ''), feeds.name) AS feed_name, feeds.url AS feed_url,
+    feeds.active AS feed_active, feeds.deactivated_at AS feed_deactivated_at, COALESCE(folders.name, '')::TEXT AS folder_name
 FROM feed_follows
 INNER JOIN users
 ON feed_follows.user_id = users.id
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
-WHERE feed_follows.user_id = $1;
+LEFT JOIN folders
+ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS LAST, feed_name;
+
+-- name: MarkFollowsNotified :exec
+UPDATE feed_follows SET
+    notified_at = $2
+WHERE user_id = $1;
+
+-- name: SetFollowTitle :execrows
+UPDATE feed_follows SET
+    title = $3,
+    updated_at = $4
+WHERE user_id = $1
+AND feed_id = $2;
 
--- name: DeleteFollow :exec
+-- name: DeleteFollow :execrows
 DELETE FROM feed_follows
 WHERE feed_follows.feed_id = (
     SELECT feeds.id
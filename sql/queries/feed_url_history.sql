@@ -0,0 +1,30 @@
+-- name: AddFeedURLHistory :exec
+INSERT INTO feed_url_history (url, feed_id, moved_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (url) DO UPDATE SET
+    feed_id = EXCLUDED.feed_id,
+    moved_at = EXCLUDED.moved_at;
+
+-- name: DeleteFeedURLHistory :exec
+DELETE FROM feed_url_history
+WHERE url = $1;
+
+-- name: MoveFeedURLHistory :exec
+UPDATE feed_url_history SET
+    feed_id = @new_feed_id
+WHERE feed_id = @old_feed_id;
+
+-- name: GetFeedMovesForUser :many
+SELECT COALESCE(NULLIF(feed_follows.title, ''), feeds.name) AS feed_name, feed_url_history.url AS old_url, feeds.url AS new_url, feed_url_history.moved_at
+FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+INNER JOIN feed_url_history
+ON feed_url_history.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+AND feed_url_history.moved_at > feed_follows.notified_at
+ORDER BY feed_url_history.moved_at;
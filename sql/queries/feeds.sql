@@ -1,12 +1,13 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, last_fetched_at)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
 RETURNING *;
 
@@ -15,8 +16,89 @@ SELECT name FROM users
 WHERE users.id = $1;
 
 -- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds;
+SELECT id, name, url, user_id FROM feeds;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: GetFeedsByNameOrIDPrefix :many
+-- Name matches come first so the limit can't hide them behind feeds whose ids happen to match
+SELECT * FROM feeds
+WHERE lower(name) = lower(@name::TEXT)
+OR (@id_prefix::TEXT <> '' AND id::TEXT LIKE @id_prefix::TEXT || '%')
+ORDER BY lower(name) = lower(@name::TEXT) DESC, name
+LIMIT 10;
+
+-- name: UpdateFeed :one
+UPDATE feeds SET
+    name = $2,
+    url = $3,
+    updated_at = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: MoveFeedFollows :execrows
+UPDATE feed_follows SET
+    feed_id = @new_feed_id,
+    updated_at = @updated_at
+WHERE feed_id = @old_feed_id
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows
+    WHERE feed_id = @new_feed_id
+);
+
+-- name: MoveFeedPosts :execrows
+UPDATE posts SET
+    feed_id = @new_feed_id,
+    updated_at = @updated_at
+WHERE feed_id = @old_feed_id;
+
+-- name: SetFeedFullText :exec
+UPDATE feeds SET
+    fetch_full_text = $2,
+    updated_at = $3
+WHERE url = $1;
 
 -- name: URLLookup :one
 SELECT name, id FROM feeds
-WHERE url = $1;
\ No newline at end of file
+WHERE url = $1
+OR id = (
+    SELECT feed_id FROM feed_url_history
+    WHERE feed_url_history.url = $1
+)
+ORDER BY url = $1 DESC
+LIMIT 1;
+
+-- name: SetFeedURL :exec
+UPDATE feeds SET
+    url = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: SetFeedActive :exec
+UPDATE feeds SET
+    active = $2,
+    deactivated_at = $3,
+    updated_at = $4
+WHERE id = $1;
+
+-- name: DeleteAllFeeds :execrows
+DELETE FROM feeds;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds SET
+    retention_days = $2,
+    retention_posts = $3,
+    updated_at = $4
+WHERE id = $1;
+
+-- name: ReassignFeeds :execrows
+UPDATE feeds SET
+    user_id = @new_user_id,
+    updated_at = @updated_at
+WHERE user_id = @old_user_id;
@@ -0,0 +1,31 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1
+AND lower(name) = lower($2);
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: DeleteFolder :exec
+DELETE FROM folders
+WHERE id = $1;
+
+-- name: SetFollowFolder :execrows
+UPDATE feed_follows SET
+    folder_id = $3,
+    updated_at = $4
+WHERE user_id = $1
+AND feed_id = $2;
@@ -5,5 +5,7 @@ UPDATE feeds SET
 WHERE feeds.id = $2;
 
 -- name: GetNextFeedToFetch :one
-SELECT id, url, name FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST;
+SELECT id, url, name, fetch_full_text FROM feeds
+WHERE active
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1;
@@ -0,0 +1,50 @@
+-- name: GetFeedsWithUnreadCount :many
+SELECT feeds.id, COALESCE(NULLIF(feed_follows.title, ''), feeds.name) AS name, feeds.url, feeds.fetch_full_text,
+    COUNT(posts.id) FILTER (WHERE post_states.read IS NOT TRUE) AS unread
+FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN posts
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id
+AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id, feeds.name, feed_follows.title, feeds.url, feeds.fetch_full_text
+ORDER BY name;
+
+-- name: GetPostsWithState :many
+SELECT posts.id, posts.title, posts.description, posts.content, posts.url, posts.published_at, posts.feed_id, COALESCE(NULLIF(feed_follows.title, ''), feeds.name) AS feed_title,
+    COALESCE(post_states.read, FALSE)::BOOLEAN AS read,
+    COALESCE(post_states.starred, FALSE)::BOOLEAN AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id
+AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = @user_id
+AND (sqlc.narg('feed_id')::UUID IS NULL OR posts.feed_id = sqlc.narg('feed_id')::UUID)
+AND (NOT @starred_only::BOOLEAN OR COALESCE(post_states.starred, FALSE))
+AND (@search::TEXT = ''
+    OR posts.title ILIKE '%' || @search::TEXT || '%'
+    OR posts.description ILIKE '%' || @search::TEXT || '%'
+    OR posts.content ILIKE '%' || @search::TEXT || '%')
+ORDER BY posts.published_at DESC
+LIMIT @max_posts;
+
+-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read, updated_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, post_id) DO UPDATE SET
+    read = EXCLUDED.read,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred, updated_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, post_id) DO UPDATE SET
+    starred = EXCLUDED.starred,
+    updated_at = EXCLUDED.updated_at;
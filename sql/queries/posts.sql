@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content)
 VALUES (
     $1,
     $2,
@@ -8,14 +8,89 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9
 )
 RETURNING *;
 
 -- name: GetPostsForUser :many
-SELECT posts.title, posts.description, posts.url
+-- CROSS JOIN is an inner join to PostgreSQL, but it makes SQLite walk posts newest first
+-- through the index and stop at the limit, rather than sorting every post the user follows
+SELECT posts.id, posts.title, posts.description, posts.content, posts.url, posts.published_at, COALESCE(NULLIF(feed_follows.title, ''), feeds.name) AS feed_title,
+    COALESCE((SELECT string_agg(post_authors.name, chr(31)) FROM post_authors WHERE post_authors.post_id = posts.id), '')::TEXT AS authors,
+    COALESCE((SELECT string_agg(post_categories.name, chr(31)) FROM post_categories WHERE post_categories.post_id = posts.id), '')::TEXT AS categories,
+    COALESCE(post_states.read, FALSE)::BOOLEAN AS read,
+    COALESCE(post_states.starred, FALSE)::BOOLEAN AS starred
 FROM posts
+CROSS JOIN feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders
+ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id
+AND post_states.user_id = feed_follows.user_id
+WHERE posts.feed_id = feed_follows.feed_id
+AND feed_follows.user_id = @user_id
+AND (@folder::TEXT = '' OR lower(folders.name) = lower(@folder::TEXT))
+AND (@author::TEXT = '' OR EXISTS (
+    SELECT 1 FROM post_authors
+    WHERE post_authors.post_id = posts.id
+    AND post_authors.name ILIKE '%' || @author::TEXT || '%'
+))
+AND (@category::TEXT = '' OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    AND lower(post_categories.name) = lower(@category::TEXT)
+))
+AND (NOT @starred_only::BOOLEAN OR COALESCE(post_states.starred, FALSE))
+AND (sqlc.narg('before_published_at')::TEXT IS NULL
+    OR (posts.published_at, posts.id) < (sqlc.narg('before_published_at')::TEXT, sqlc.narg('before_id')::UUID))
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT @max_posts;
+
+-- name: GetPostsByIDPrefix :many
+SELECT posts.id, posts.title, posts.url, posts.published_at FROM posts
 INNER JOIN feed_follows
-ON posts.feed_id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY published_at DESC;
\ No newline at end of file
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = @user_id
+AND posts.id::TEXT LIKE @prefix::TEXT || '%'
+ORDER BY posts.id
+LIMIT 10;
+
+-- name: UpdatePostContent :exec
+UPDATE posts SET
+    content = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: CreatePostAuthor :exec
+INSERT INTO post_authors (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteAllPosts :execrows
+DELETE FROM posts;
+
+-- name: GetPostIDNeighbours :one
+-- The ids either side of @id among the posts the user follows, which decide how much of it is needed to tell it apart
+SELECT
+    (SELECT posts.id FROM posts
+        INNER JOIN feed_follows
+        ON feed_follows.feed_id = posts.feed_id
+        WHERE feed_follows.user_id = @user_id
+        AND posts.id < @id
+        ORDER BY posts.id DESC
+        LIMIT 1) AS previous_id,
+    (SELECT posts.id FROM posts
+        INNER JOIN feed_follows
+        ON feed_follows.feed_id = posts.feed_id
+        WHERE feed_follows.user_id = @user_id
+        AND posts.id > @id
+        ORDER BY posts.id
+        LIMIT 1) AS next_id;
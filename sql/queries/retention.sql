@@ -0,0 +1,50 @@
+-- name: GetPostsToPrune :many
+-- Age goes by published_at like the ranking does, so old posts a feed backfills go on the next prune.
+-- The dates compare as text, which is off by at most the feed's utc offset.
+SELECT ranked.id FROM (
+    SELECT posts.id, posts.published_at,
+        ROW_NUMBER() OVER (ORDER BY posts.published_at DESC, posts.id) AS position
+    FROM posts
+    WHERE posts.feed_id = @feed_id
+) AS ranked
+WHERE (sqlc.narg('published_before')::TEXT IS NULL OR ranked.published_at < sqlc.narg('published_before'))
+AND (sqlc.narg('keep_newest')::BIGINT IS NULL OR ranked.position > sqlc.narg('keep_newest'))
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = ranked.id
+    AND post_states.starred
+)
+ORDER BY ranked.position;
+
+-- name: AddPrunedPost :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT url, feed_id, $2 FROM posts
+WHERE id = $1
+ON CONFLICT (url) DO NOTHING;
+
+-- name: ArchivePost :exec
+INSERT INTO archived_posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, archived_at)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, $2 FROM posts
+WHERE id = $1
+ON CONFLICT (id) DO NOTHING;
+
+-- name: DeletePost :exec
+DELETE FROM posts
+WHERE id = $1;
+
+-- name: IsPostPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE url = $1
+);
+
+-- name: MoveArchivedPosts :execrows
+UPDATE archived_posts SET
+    feed_id = @new_feed_id,
+    updated_at = @updated_at
+WHERE feed_id = @old_feed_id;
+
+-- name: MovePrunedPosts :exec
+UPDATE pruned_posts SET
+    feed_id = @new_feed_id
+WHERE feed_id = @old_feed_id;
@@ -1,10 +1,11 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 )
 RETURNING *;
 
@@ -16,4 +17,14 @@ WHERE name = $1;
 DELETE FROM users;
 
 -- name: GetUsers :many
-SELECT name FROM users;
\ No newline at end of file
+SELECT name FROM users;
+
+-- name: SetUserAdmin :execrows
+UPDATE users SET
+    is_admin = $2,
+    updated_at = $3
+WHERE name = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE name = $1;
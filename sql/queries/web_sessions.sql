@@ -0,0 +1,26 @@
+-- name: CreateWebSession :one
+INSERT INTO web_sessions (id, created_at, expires_at, api_token_id, token_hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetUserByWebSession :one
+SELECT users.* FROM users
+INNER JOIN api_tokens
+ON api_tokens.user_id = users.id
+INNER JOIN web_sessions
+ON web_sessions.api_token_id = api_tokens.id
+WHERE web_sessions.token_hash = $1 AND web_sessions.expires_at > $2;
+
+-- name: DeleteWebSession :execrows
+DELETE FROM web_sessions
+WHERE token_hash = $1;
+
+-- name: DeleteExpiredWebSessions :execrows
+DELETE FROM web_sessions
+WHERE expires_at <= $1;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE post_authors (
+    post_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    UNIQUE (post_id, name)
+);
+
+CREATE TABLE post_categories (
+    post_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    UNIQUE (post_id, name)
+);
+
+-- +goose Down
+DROP TABLE post_categories;
+DROP TABLE post_authors;
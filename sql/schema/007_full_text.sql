@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN fetch_full_text BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE posts
+ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content;
+
+ALTER TABLE feeds
+DROP COLUMN fetch_full_text;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE post_states (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    starred BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_states;
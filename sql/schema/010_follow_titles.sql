@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD COLUMN title TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN title;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The first user to register looks after the install
+UPDATE users SET is_admin = TRUE
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- Deleting a feed should take its posts with it
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey,
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey,
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feeds(id);
+
+ALTER TABLE users
+DROP COLUMN is_admin;
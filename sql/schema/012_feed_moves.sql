@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN active BOOLEAN NOT NULL DEFAULT TRUE,
+ADD COLUMN deactivated_at TIMESTAMP;
+
+CREATE TABLE feed_url_history (
+    url TEXT PRIMARY KEY,
+    feed_id UUID NOT NULL,
+    moved_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+ALTER TABLE feed_follows
+ADD COLUMN notified_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN notified_at;
+
+DROP TABLE feed_url_history;
+
+ALTER TABLE feeds
+DROP COLUMN deactivated_at,
+DROP COLUMN active;
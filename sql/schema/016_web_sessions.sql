@@ -0,0 +1,14 @@
+-- +goose Up
+-- Logins to the web reader. The cookie holds a session token of its own rather than the API token
+-- the user logged in with, and only a hash of it is kept. Revoking that API token ends its sessions.
+CREATE TABLE web_sessions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    api_token_id UUID NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    FOREIGN KEY (api_token_id) REFERENCES api_tokens(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE web_sessions;
@@ -0,0 +1,13 @@
+package schema
+
+import "embed"
+
+// The goose annotated PostgreSQL migrations, numbered in the order they are applied
+//
+//go:embed *.sql
+var FS embed.FS
+
+// The same schema for SQLite, which starts out at the version PostgreSQL had reached when it was added
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -0,0 +1,105 @@
+-- SQLite support starts at version 12, so this creates everything
+-- migrations 001 to 012 do for PostgreSQL in one go.
+
+-- +goose Up
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT UNIQUE NOT NULL,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE feeds (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    user_id TEXT NOT NULL,
+    last_fetched_at TIMESTAMP,
+    fetch_full_text BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    deactivated_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE folders (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follows (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    feed_id TEXT NOT NULL,
+    folder_id TEXT REFERENCES folders(id) ON DELETE SET NULL,
+    title TEXT NOT NULL DEFAULT '',
+    notified_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+    UNIQUE (user_id, feed_id)
+);
+
+CREATE TABLE feed_url_history (
+    url TEXT PRIMARY KEY,
+    feed_id TEXT NOT NULL,
+    moved_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TABLE posts (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL,
+    published_at TEXT NOT NULL,
+    feed_id TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TABLE post_authors (
+    post_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    UNIQUE (post_id, name)
+);
+
+CREATE TABLE post_categories (
+    post_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    UNIQUE (post_id, name)
+);
+
+CREATE TABLE post_states (
+    user_id TEXT NOT NULL,
+    post_id TEXT NOT NULL,
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    starred BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_states;
+DROP TABLE post_categories;
+DROP TABLE post_authors;
+DROP TABLE posts;
+DROP TABLE feed_url_history;
+DROP TABLE feed_follows;
+DROP TABLE folders;
+DROP TABLE feeds;
+DROP TABLE users;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- Per-feed overrides of the retention settings in the config file
+ALTER TABLE feeds ADD COLUMN retention_days INTEGER;
+ALTER TABLE feeds ADD COLUMN retention_posts INTEGER;
+
+-- Urls of posts removed by retention, so the aggregator doesn't fetch them again
+CREATE TABLE pruned_posts (
+    url TEXT PRIMARY KEY,
+    feed_id TEXT NOT NULL,
+    pruned_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- Posts moved out of the way by retention when archiving is turned on
+CREATE TABLE archived_posts (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT NOT NULL,
+    published_at TEXT NOT NULL,
+    feed_id TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    archived_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE archived_posts;
+
+DROP TABLE pruned_posts;
+
+ALTER TABLE feeds DROP COLUMN retention_posts;
+ALTER TABLE feeds DROP COLUMN retention_days;
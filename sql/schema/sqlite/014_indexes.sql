@@ -0,0 +1,24 @@
+-- +goose Up
+-- browse reads a user's newest posts, either across every feed or one feed at a time
+CREATE INDEX posts_published_at_idx ON posts (published_at, id);
+CREATE INDEX posts_feed_id_published_at_idx ON posts (feed_id, published_at);
+
+-- Posts are joined to their followers by feed, the unique (user_id, feed_id) only helps going the other way
+CREATE INDEX feed_follows_feed_id_idx ON feed_follows (feed_id);
+
+-- Pruning skips starred posts, and deleting a post clears its states
+CREATE INDEX post_states_post_id_idx ON post_states (post_id);
+
+-- The aggregator picks the active feed fetched longest ago, SQLite sorts nulls first already
+CREATE INDEX feeds_last_fetched_at_idx ON feeds (last_fetched_at) WHERE active;
+
+-- +goose Down
+DROP INDEX feeds_last_fetched_at_idx;
+
+DROP INDEX post_states_post_id_idx;
+
+DROP INDEX feed_follows_feed_id_idx;
+
+DROP INDEX posts_feed_id_published_at_idx;
+
+DROP INDEX posts_published_at_idx;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Tokens that let other programs use the HTTP API as a user. Only a hash of each token is kept.
+CREATE TABLE api_tokens (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
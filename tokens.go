@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const tokenUsage = `usage:
+  token create <name>   create a token for the HTTP API, it's only shown once
+  token ls              list your tokens
+  token revoke <name>   delete a token, programs using it lose access`
+
+// Tokens start with this so they're easy to recognise, e.g. by secret scanners
+const tokenPrefix = "gator_"
+
+func handlerToken(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return errors.New(tokenUsage)
+	}
+
+	args := cmd.arguments[1:]
+
+	switch cmd.arguments[0] {
+	case "create":
+		if len(args) != 1 {
+			return fmt.Errorf("expecting 1 argument (token name)")
+		}
+
+		token, err := newToken()
+		if err != nil {
+			return fmt.Errorf("error creating token: %w", err)
+		}
+		_, err = s.db.CreateAPIToken(context.Background(), database.CreateAPITokenParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UserID:    user.ID,
+			Name:      args[0],
+			TokenHash: hashToken(token),
+		})
+		if database.IsUniqueViolation(err) {
+			return fmt.Errorf("you already have a token called %s", args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("error creating token: %w", err)
+		}
+
+		fmt.Fprintf(s.out, "Created token %s for %s, it won't be shown again:\n\n  %s\n\n", args[0], user.Name, token)
+		fmt.Fprintln(s.out, "Send it with each API request as: Authorization: Bearer <token>")
+
+	case "ls":
+		tokens, err := s.db.GetAPITokensForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("error getting tokens: %w", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Fprintln(s.out, "You have no tokens, create one with: gator token create <name>")
+		}
+		for _, token := range tokens {
+			fmt.Fprintf(s.out, "  %-20s  created %s\n", token.Name, token.CreatedAt.Format(time.DateTime))
+		}
+
+	case "revoke":
+		if len(args) != 1 {
+			return fmt.Errorf("expecting 1 argument (token name)")
+		}
+		deleted, err := s.db.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{
+			UserID: user.ID,
+			Name:   args[0],
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting token: %w", err)
+		}
+		if deleted == 0 {
+			return fmt.Errorf("you have no token called %s", args[0])
+		}
+		fmt.Fprintf(s.out, "Revoked %s\n", args[0])
+
+	default:
+		return errors.New(tokenUsage)
+	}
+
+	return nil
+}
+
+func newToken() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return tokenPrefix + hex.EncodeToString(secret), nil
+}
+
+// Only hashes are stored, so the tokens can't be read back out of the database. The tokens are
+// random enough that a plain hash is as good as a slow one.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
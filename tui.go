@@ -0,0 +1,635 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/render"
+	"github.com/Daxin319/Gator/internal/sanitize"
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+const (
+	paneFeeds = iota
+	panePosts
+	paneBody
+)
+
+const (
+	tuiMaxPosts = 500
+	tuiHelp     = "j/k move  h/l switch pane  enter open  m read  s star  o browser  r refresh  / search  q quit"
+
+	reverseVideo = "\x1b[7m"
+	boldText     = "\x1b[1m"
+	resetStyle   = "\x1b[0m"
+)
+
+// An entry in the feeds pane. All and Starred are virtual entries with no feed behind them.
+type tuiFeed struct {
+	label   string
+	unread  int64
+	starred bool
+	feed    *database.GetFeedsWithUnreadCountRow
+}
+
+type refreshResult struct {
+	name string
+	err  error
+}
+
+type tui struct {
+	s    *state
+	user database.User
+	out  *os.File
+
+	feeds []tuiFeed
+	posts []database.GetPostsWithStateRow
+
+	focus      int
+	feedIndex  int
+	feedTop    int
+	postIndex  int
+	postTop    int
+	bodyScroll int
+	bodyLines  []string
+
+	search    string
+	searching bool
+	input     string
+	status    string
+
+	width  int
+	height int
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("tui needs to be run in a terminal")
+	}
+
+	t := &tui{
+		s:      s,
+		user:   user,
+		out:    os.Stdout,
+		status: tuiHelp,
+	}
+
+	if err := t.loadFeeds(); err != nil {
+		return fmt.Errorf("error getting followed feeds: %w", err)
+	}
+	if err := t.loadPosts(); err != nil {
+		return fmt.Errorf("error getting posts: %w", err)
+	}
+
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("error setting up terminal: %w", err)
+	}
+	defer term.Restore(int(os.Stdin.Fd()), oldState)
+
+	// Switch to the alternate screen and hide the cursor, then put everything back on the way out
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+
+	// Refreshing a feed logs skipped items, which would scribble over the screen
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	refreshed := make(chan refreshResult)
+
+	for {
+		t.draw()
+
+		select {
+		case key, ok := <-keys:
+			if !ok || !t.handleKey(key, refreshed) {
+				return nil
+			}
+		case <-resize:
+		case result := <-refreshed:
+			if result.err != nil {
+				t.status = fmt.Sprintf("Error refreshing %s: %v", result.name, result.err)
+			} else {
+				t.status = fmt.Sprintf("Refreshed %s", result.name)
+			}
+			t.reload()
+		}
+	}
+}
+
+// Handles a single keypress and reports whether the tui should keep running
+func (t *tui) handleKey(key string, refreshed chan<- refreshResult) bool {
+	if t.searching {
+		switch key {
+		case "esc", "ctrl-c":
+			t.searching = false
+			t.status = tuiHelp
+		case "enter":
+			t.searching = false
+			t.search = strings.TrimSpace(t.input)
+			t.postIndex, t.postTop, t.bodyScroll = 0, 0, 0
+			t.focus = panePosts
+			t.status = tuiHelp
+			t.reload()
+		case "backspace":
+			if len(t.input) > 0 {
+				_, size := utf8.DecodeLastRuneInString(t.input)
+				t.input = t.input[:len(t.input)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				t.input += key
+			}
+		}
+		return true
+	}
+
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "j", "down":
+		t.move(1)
+	case "k", "up":
+		t.move(-1)
+	case "ctrl-d", "pgdown":
+		t.move(t.paneHeight() / 2)
+	case "ctrl-u", "pgup":
+		t.move(-t.paneHeight() / 2)
+	case "g", "home":
+		t.move(-1 << 30)
+	case "G", "end":
+		t.move(1 << 30)
+	case "h", "left":
+		if t.focus > paneFeeds {
+			t.focus--
+		}
+	case "l", "right", "enter":
+		t.open()
+	case "tab":
+		t.focus = (t.focus + 1) % 3
+	case "m":
+		if post := t.currentPost(); post != nil {
+			t.setRead(!post.Read)
+		}
+	case "s":
+		t.toggleStarred()
+	case "o":
+		if post := t.currentPost(); post != nil {
+			if err := openInBrowser(post.Url); err != nil {
+				t.status = err.Error()
+			} else {
+				t.setRead(true)
+				t.status = "Opened " + post.Url
+			}
+		}
+	case "r":
+		t.refresh(refreshed)
+	case "/":
+		t.searching = true
+		t.input = t.search
+	case "esc":
+		if t.search != "" {
+			t.search = ""
+			t.postIndex, t.postTop, t.bodyScroll = 0, 0, 0
+			t.reload()
+		}
+	case "?":
+		t.status = tuiHelp
+	}
+	return true
+}
+
+// Moves the selection in the focused pane, or scrolls the post body
+func (t *tui) move(delta int) {
+	switch t.focus {
+	case paneFeeds:
+		index := clamp(t.feedIndex+delta, 0, len(t.feeds)-1)
+		if index != t.feedIndex {
+			t.feedIndex = index
+			t.search = ""
+			t.postIndex, t.postTop, t.bodyScroll = 0, 0, 0
+			t.showError(t.loadPosts())
+		}
+	case panePosts:
+		index := clamp(t.postIndex+delta, 0, len(t.posts)-1)
+		if index != t.postIndex {
+			t.postIndex = index
+			t.bodyScroll = 0
+		}
+	case paneBody:
+		t.bodyScroll = clamp(t.bodyScroll+delta, 0, len(t.bodyLines)-1)
+	}
+}
+
+// Moves focus one pane to the right. Opening a post marks it read.
+func (t *tui) open() {
+	switch t.focus {
+	case paneFeeds:
+		t.focus = panePosts
+	case panePosts:
+		if t.currentPost() != nil {
+			t.focus = paneBody
+			t.setRead(true)
+		}
+	}
+}
+
+func (t *tui) setRead(read bool) {
+	post := t.currentPost()
+	if post == nil || post.Read == read {
+		return
+	}
+
+	err := t.s.db.SetPostRead(context.Background(), database.SetPostReadParams{
+		UserID:    t.user.ID,
+		PostID:    post.ID,
+		Read:      read,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.showError(err)
+		return
+	}
+
+	post.Read = read
+	t.showError(t.loadFeeds())
+}
+
+func (t *tui) toggleStarred() {
+	post := t.currentPost()
+	if post == nil {
+		return
+	}
+
+	err := t.s.db.SetPostStarred(context.Background(), database.SetPostStarredParams{
+		UserID:    t.user.ID,
+		PostID:    post.ID,
+		Starred:   !post.Starred,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.showError(err)
+		return
+	}
+
+	post.Starred = !post.Starred
+}
+
+// Fetches the selected feed, or the feed of the selected post, in the background
+func (t *tui) refresh(refreshed chan<- refreshResult) {
+	var feedID uuid.UUID
+	if entry := t.feeds[t.feedIndex]; t.focus == paneFeeds && entry.feed != nil {
+		feedID = entry.feed.ID
+	} else if post := t.currentPost(); post != nil {
+		feedID = post.FeedID
+	} else if entry.feed != nil {
+		feedID = entry.feed.ID
+	}
+
+	for _, entry := range t.feeds {
+		if entry.feed == nil || entry.feed.ID != feedID {
+			continue
+		}
+
+		feed := database.GetNextFeedToFetchRow{
+			ID:            entry.feed.ID,
+			Url:           entry.feed.Url,
+			Name:          entry.feed.Name,
+			FetchFullText: entry.feed.FetchFullText,
+		}
+		t.status = fmt.Sprintf("Refreshing %s...", feed.Name)
+
+		go func() {
+			refreshed <- refreshResult{name: feed.Name, err: scrapeFeed(t.s, feed)}
+		}()
+		return
+	}
+
+	t.status = "Select a feed to refresh"
+}
+
+// Reloads feeds and posts, keeping the selected post selected if it's still there
+func (t *tui) reload() {
+	var selected uuid.UUID
+	if post := t.currentPost(); post != nil {
+		selected = post.ID
+	}
+
+	if err := t.loadFeeds(); err != nil {
+		t.showError(err)
+		return
+	}
+	if err := t.loadPosts(); err != nil {
+		t.showError(err)
+		return
+	}
+
+	for i, post := range t.posts {
+		if post.ID == selected {
+			t.postIndex = i
+		}
+	}
+}
+
+func (t *tui) loadFeeds() error {
+	rows, err := t.s.db.GetFeedsWithUnreadCount(context.Background(), t.user.ID)
+	if err != nil {
+		return err
+	}
+
+	total := int64(0)
+	for _, row := range rows {
+		total += row.Unread
+	}
+
+	t.feeds = []tuiFeed{
+		{label: "All", unread: total},
+		{label: "Starred", starred: true},
+	}
+	for i := range rows {
+		t.feeds = append(t.feeds, tuiFeed{
+			label:  rows[i].Name,
+			unread: rows[i].Unread,
+			feed:   &rows[i],
+		})
+	}
+	t.feedIndex = clamp(t.feedIndex, 0, len(t.feeds)-1)
+
+	return nil
+}
+
+// Loads the posts for the selected feeds entry, or for the current search across every feed
+func (t *tui) loadPosts() error {
+	arg := database.GetPostsWithStateParams{
+		UserID:   t.user.ID,
+		Search:   t.search,
+		MaxPosts: tuiMaxPosts,
+	}
+
+	if t.search == "" {
+		entry := t.feeds[t.feedIndex]
+		arg.StarredOnly = entry.starred
+		if entry.feed != nil {
+			arg.FeedID = uuid.NullUUID{UUID: entry.feed.ID, Valid: true}
+		}
+	}
+
+	posts, err := t.s.db.GetPostsWithState(context.Background(), arg)
+	if err != nil {
+		return err
+	}
+
+	t.posts = posts
+	t.postIndex = clamp(t.postIndex, 0, len(t.posts)-1)
+
+	return nil
+}
+
+func (t *tui) currentPost() *database.GetPostsWithStateRow {
+	if t.postIndex < 0 || t.postIndex >= len(t.posts) {
+		return nil
+	}
+	return &t.posts[t.postIndex]
+}
+
+func (t *tui) showError(err error) {
+	if err != nil {
+		t.status = "Error: " + err.Error()
+	}
+}
+
+func (t *tui) paneHeight() int {
+	return max(t.height-2, 1)
+}
+
+// Redraws the whole screen: a header row, the three panes, and a status line
+func (t *tui) draw() {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+	t.width, t.height = width, height
+
+	if width < 60 || height < 5 {
+		fmt.Fprint(t.out, "\x1b[H\x1b[2J"+"Terminal too small")
+		return
+	}
+
+	feedsWidth := clamp(width/5, 16, 32)
+	postsWidth := clamp((width-feedsWidth)*2/5, 24, 60)
+	bodyWidth := width - feedsWidth - postsWidth - 2
+	rows := t.paneHeight()
+
+	t.feedTop = scrollTo(t.feedIndex, t.feedTop, rows)
+	t.postTop = scrollTo(t.postIndex, t.postTop, rows)
+	t.bodyLines = t.renderBody(bodyWidth - 2)
+	t.bodyScroll = clamp(t.bodyScroll, 0, len(t.bodyLines)-1)
+
+	separator := "│"
+
+	postsTitle := " Posts"
+	if t.search != "" {
+		postsTitle = fmt.Sprintf(" Search: %s", t.search)
+	} else if len(t.feeds) > 0 {
+		postsTitle = " " + t.feeds[t.feedIndex].label
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\x1b[H")
+	screen.WriteString(t.header(" Feeds", feedsWidth, paneFeeds) + separator +
+		t.header(postsTitle, postsWidth, panePosts) + separator +
+		t.header(" "+t.user.Name, bodyWidth, paneBody) + "\r\n")
+
+	for row := 0; row < rows; row++ {
+		screen.WriteString(t.feedLine(t.feedTop+row, feedsWidth))
+		screen.WriteString(separator)
+		screen.WriteString(t.postLine(t.postTop+row, postsWidth))
+		screen.WriteString(separator)
+		if line := t.bodyScroll + row; line < len(t.bodyLines) {
+			screen.WriteString(render.Fit(" "+t.bodyLines[line], bodyWidth))
+		} else {
+			screen.WriteString(strings.Repeat(" ", bodyWidth))
+		}
+		screen.WriteString("\r\n")
+	}
+
+	status := t.status
+	if t.searching {
+		status = "/" + t.input + "█"
+	}
+	screen.WriteString(reverseVideo + render.Fit(" "+sanitize.Text(status), width) + resetStyle)
+
+	fmt.Fprint(t.out, screen.String())
+}
+
+func (t *tui) header(title string, width int, pane int) string {
+	if t.focus == pane {
+		return reverseVideo + render.Fit(sanitize.Text(title), width) + resetStyle
+	}
+	return boldText + render.Fit(sanitize.Text(title), width) + resetStyle
+}
+
+func (t *tui) feedLine(index int, width int) string {
+	if index >= len(t.feeds) {
+		return strings.Repeat(" ", width)
+	}
+
+	entry := t.feeds[index]
+	count := ""
+	if entry.unread > 0 {
+		count = strconv.FormatInt(entry.unread, 10) + " "
+	}
+	line := render.Fit(" "+sanitize.Text(entry.label), width-len(count)) + count
+
+	return t.highlight(line, index == t.feedIndex, t.focus == paneFeeds)
+}
+
+func (t *tui) postLine(index int, width int) string {
+	if index >= len(t.posts) {
+		return strings.Repeat(" ", width)
+	}
+
+	post := t.posts[index]
+	marker := " ● "
+	if post.Read {
+		marker = "   "
+	}
+	star := "  "
+	if post.Starred {
+		star = "★ "
+	}
+	// Titles come straight from feeds, so any escape sequences in them are dropped before drawing
+	line := render.Fit(marker+star+sanitize.Text(post.Title), width)
+
+	return t.highlight(line, index == t.postIndex, t.focus == panePosts)
+}
+
+// Selected rows are shown in reverse video in the focused pane and bold elsewhere
+func (t *tui) highlight(line string, selected bool, focused bool) string {
+	switch {
+	case selected && focused:
+		return reverseVideo + line + resetStyle
+	case selected:
+		return boldText + line + resetStyle
+	default:
+		return line
+	}
+}
+
+// Renders the selected post as markdown wrapped to the body pane
+func (t *tui) renderBody(width int) []string {
+	post := t.currentPost()
+	if post == nil {
+		return []string{"No posts"}
+	}
+
+	_, noColor := os.LookupEnv("NO_COLOR")
+	r := render.Renderer{Width: max(width, 10), Color: !noColor}
+
+	body := post.Content
+	if body == "" {
+		body = post.Description
+	}
+
+	text := r.Heading(post.Title) +
+		r.Faint(post.FeedTitle+"    "+post.PublishedAt) + "\n\n" +
+		r.Markdown(body) + "\n" +
+		r.Link(post.Url, post.Url)
+
+	return strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n")
+}
+
+// Reads keypresses from in and sends them as names like "j", "enter" or "up"
+func readKeys(in io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+func parseKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; c {
+		case 27:
+			if i+2 < len(input) && input[i+1] == '[' {
+				sequence := map[string]string{
+					"A": "up", "B": "down", "C": "right", "D": "left",
+					"H": "home", "F": "end", "5~": "pgup", "6~": "pgdown",
+				}
+				end := i + 2
+				for end < len(input) && input[end] >= '0' && input[end] <= '9' {
+					end++
+				}
+				if end < len(input) {
+					if name, ok := sequence[string(input[i+2:end+1])]; ok {
+						keys = append(keys, name)
+					}
+					i = end
+					continue
+				}
+			}
+			keys = append(keys, "esc")
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 127, 8:
+			keys = append(keys, "backspace")
+		case 3:
+			keys = append(keys, "ctrl-c")
+		case 4:
+			keys = append(keys, "ctrl-d")
+		case 21:
+			keys = append(keys, "ctrl-u")
+		default:
+			r, size := utf8.DecodeRune(input[i:])
+			if unicode.IsPrint(r) {
+				keys = append(keys, string(r))
+			}
+			i += size - 1
+		}
+	}
+	return keys
+}
+
+// Returns the new top row of a list so that index stays on screen
+func scrollTo(index, top, rows int) int {
+	if index < top {
+		return index
+	}
+	if index >= top+rows {
+		return index - rows + 1
+	}
+	return max(top, 0)
+}
+
+func clamp(value, low, high int) int {
+	if high < low {
+		return low
+	}
+	return min(max(value, low), high)
+}
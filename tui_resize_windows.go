@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// Windows consoles don't signal resizes, the tui picks up the new size on the next keypress instead
+func notifyResize(c chan<- os.Signal) {}
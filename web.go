@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Daxin319/Gator/internal/database"
+	"github.com/Daxin319/Gator/internal/render"
+	"github.com/Daxin319/Gator/web"
+	"github.com/google/uuid"
+)
+
+const (
+	// Holds the web session token, never the API token the user logged in with
+	sessionCookie = "gator_session"
+	sessionLength = 30 * 24 * time.Hour
+
+	webMaxPosts = 200
+)
+
+// What every page of the web reader needs for its header
+type webPage struct {
+	User    *database.User
+	Feed    string
+	Starred bool
+	Search  string
+}
+
+type readerPage struct {
+	webPage
+	Entries []readerEntry
+	Posts   []readerPostItem
+	Post    *readerPost
+	// The page as it is now, and without the open post
+	URL     string
+	ListURL string
+}
+
+// An entry in the feeds sidebar. All and Starred are virtual entries with no feed behind them,
+// like in the TUI.
+type readerEntry struct {
+	Label    string
+	Unread   int64
+	URL      string
+	Selected bool
+}
+
+type readerPostItem struct {
+	database.GetPostsWithStateRow
+	URL      string
+	Selected bool
+}
+
+type readerPost struct {
+	database.GetPostsWithStateRow
+	Body template.HTML
+}
+
+type addFeedPage struct {
+	webPage
+	Name  string
+	Url   string
+	Error string
+}
+
+type messagePage struct {
+	webPage
+	Error string
+}
+
+// The feed, starred and search filters the reader is showing posts for
+type readerView struct {
+	feed    uuid.NullUUID
+	starred bool
+	search  string
+}
+
+func (v readerView) url(post uuid.UUID) string {
+	query := url.Values{}
+	if v.feed.Valid {
+		query.Set("feed", v.feed.UUID.String())
+	}
+	if v.starred {
+		query.Set("starred", "true")
+	}
+	if v.search != "" {
+		query.Set("q", v.search)
+	}
+	if post != uuid.Nil {
+		query.Set("post", post.String())
+	}
+	if len(query) == 0 {
+		return "/"
+	}
+	return "/?" + query.Encode()
+}
+
+func (v readerView) page(user database.User) webPage {
+	page := webPage{User: &user, Starred: v.starred, Search: v.search}
+	if v.feed.Valid {
+		page.Feed = v.feed.UUID.String()
+	}
+	return page
+}
+
+func addWebRoutes(s *state, mux *http.ServeMux) {
+	pages := parsePages()
+
+	static, err := fs.Sub(web.Static, "static")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		renderPage(w, pages, "login.html", http.StatusOK, messagePage{})
+	})
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		session, err := startWebSession(s, r, strings.TrimSpace(r.FormValue("token")))
+		if err != nil {
+			status, message := errorResponse(err)
+			renderPage(w, pages, "login.html", status, messagePage{Error: message})
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    session,
+			Path:     "/",
+			MaxAge:   int(sessionLength.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+	mux.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			if _, err := s.db.DeleteWebSession(r.Context(), hashToken(cookie.Value)); err != nil {
+				log.Println("serve:", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+
+	handle := func(pattern string, handler apiHandler) {
+		mux.Handle(pattern, webAuth(s, pages, handler))
+	}
+	handle("GET /{$}", webReader(pages))
+	handle("GET /feeds/new", func(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+		renderPage(w, pages, "addfeed.html", http.StatusOK, addFeedPage{webPage: webPage{User: &user}})
+		return nil
+	})
+	handle("POST /feeds/new", webAddFeed(pages))
+	handle("POST /posts/{post}/read", webSetPostState(false))
+	handle("POST /posts/{post}/starred", webSetPostState(true))
+}
+
+// Parses each page along with the layout they share
+func parsePages() map[string]*template.Template {
+	names, err := fs.Glob(web.Templates, "templates/*.html")
+	if err != nil {
+		panic(err)
+	}
+	pages := map[string]*template.Template{}
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "templates/")
+		if name == "layout.html" {
+			continue
+		}
+		pages[name] = template.Must(template.ParseFS(web.Templates, "templates/layout.html", "templates/"+name))
+	}
+	return pages
+}
+
+// Renders the page into a buffer first, so a template that fails part way through doesn't send half a page
+func renderPage(w http.ResponseWriter, pages map[string]*template.Template, name string, status int, data any) {
+	var buf bytes.Buffer
+	err := pages[name].ExecuteTemplate(&buf, "layout.html", data)
+	if err != nil {
+		log.Println("serve:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// Starts a web session for the user an API token belongs to and returns the session's own token
+// for the cookie. Revoking the API token ends the session too.
+func startWebSession(s *state, r *http.Request, apiToken string) (string, error) {
+	if apiToken == "" {
+		return "", apiErrorf(http.StatusUnauthorized, "missing token, create one with: gator token create <name>")
+	}
+	token, err := s.db.GetAPIToken(r.Context(), hashToken(apiToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", apiErrorf(http.StatusUnauthorized, "unknown or revoked token")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	session, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	// Sessions nobody logged out of are cleared out as new ones start
+	if _, err := s.db.DeleteExpiredWebSessions(r.Context(), now); err != nil {
+		return "", err
+	}
+	_, err = s.db.CreateWebSession(r.Context(), database.CreateWebSessionParams{
+		ID:         uuid.New(),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(sessionLength),
+		ApiTokenID: token.ID,
+		TokenHash:  hashToken(session),
+	})
+	return session, err
+}
+
+// Works out the user from the session cookie, sending anyone without a current session to the
+// login page, then runs handler for them and shows any error it returns as a page
+func webAuth(s *state, pages map[string]*template.Template, handler apiHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := s.db.GetUserByWebSession(r.Context(), database.GetUserByWebSessionParams{
+			TokenHash: hashToken(cookie.Value),
+			ExpiresAt: time.Now().UTC(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			status, message := errorResponse(err)
+			renderPage(w, pages, "error.html", status, messagePage{Error: message})
+			return
+		}
+
+		err = handler(s, w, r, user)
+		if err != nil {
+			status, message := errorResponse(err)
+			renderPage(w, pages, "error.html", status, messagePage{webPage: webPage{User: &user}, Error: message})
+		}
+	})
+}
+
+// The three pane reader: feeds, the posts in the selected one, and the open post. Showing a post
+// changes nothing, the post list marks it read through the read endpoint on the way here.
+func webReader(pages map[string]*template.Template) apiHandler {
+	return func(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+		query := r.URL.Query()
+		view := readerView{
+			search: strings.TrimSpace(query.Get("q")),
+		}
+		if value := query.Get("starred"); value != "" {
+			view.starred, _ = strconv.ParseBool(value)
+		}
+		if value := query.Get("feed"); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return lookupErrorf("no feed with id %s", value)
+			}
+			view.feed = uuid.NullUUID{UUID: id, Valid: true}
+		}
+
+		var open uuid.UUID
+		if value := query.Get("post"); value != "" {
+			post, err := lookupPost(s, user, value)
+			if err != nil {
+				return err
+			}
+			open = post.ID
+		}
+
+		feeds, err := s.db.GetFeedsWithUnreadCount(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+		posts, err := s.db.GetPostsWithState(r.Context(), database.GetPostsWithStateParams{
+			UserID:      user.ID,
+			FeedID:      view.feed,
+			StarredOnly: view.starred,
+			Search:      view.search,
+			MaxPosts:    webMaxPosts,
+		})
+		if err != nil {
+			return err
+		}
+
+		page := readerPage{
+			webPage: view.page(user),
+			URL:     view.url(open),
+			ListURL: view.url(uuid.Nil),
+		}
+
+		total := int64(0)
+		for _, feed := range feeds {
+			total += feed.Unread
+		}
+		all := readerView{search: view.search}
+		starred := readerView{starred: true, search: view.search}
+		page.Entries = []readerEntry{
+			{Label: "All", Unread: total, URL: all.url(uuid.Nil), Selected: !view.feed.Valid && !view.starred},
+			{Label: "Starred", URL: starred.url(uuid.Nil), Selected: !view.feed.Valid && view.starred},
+		}
+		for _, feed := range feeds {
+			entry := readerView{feed: uuid.NullUUID{UUID: feed.ID, Valid: true}, search: view.search}
+			page.Entries = append(page.Entries, readerEntry{
+				Label:    feed.Name,
+				Unread:   feed.Unread,
+				URL:      entry.url(uuid.Nil),
+				Selected: view.feed.Valid && view.feed.UUID == feed.ID,
+			})
+		}
+
+		for _, post := range posts {
+			page.Posts = append(page.Posts, readerPostItem{
+				GetPostsWithStateRow: post,
+				URL:                  view.url(post.ID),
+				Selected:             post.ID == open,
+			})
+			if post.ID != open {
+				continue
+			}
+			body := post.Content
+			if body == "" {
+				body = post.Description
+			}
+			page.Post = &readerPost{
+				GetPostsWithStateRow: post,
+				// The body was sanitized when it was fetched, and render.HTML escapes it again
+				Body: template.HTML(render.HTML(body)),
+			}
+		}
+		if open != uuid.Nil && page.Post == nil {
+			return lookupErrorf("the post isn't in this list, open it from All")
+		}
+
+		renderPage(w, pages, "reader.html", http.StatusOK, page)
+		return nil
+	}
+}
+
+// Adds a feed from the form, showing the form again with what went wrong if it can't be added
+func webAddFeed(pages map[string]*template.Template) apiHandler {
+	return func(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+		name := strings.TrimSpace(r.FormValue("name"))
+		feedURL := strings.TrimSpace(r.FormValue("url"))
+
+		feed, err := addFeed(s, user, name, feedURL)
+		if err != nil {
+			status, message := errorResponse(err)
+			renderPage(w, pages, "addfeed.html", status, addFeedPage{
+				webPage: webPage{User: &user},
+				Name:    name,
+				Url:     feedURL,
+				Error:   message,
+			})
+			return nil
+		}
+
+		view := readerView{feed: uuid.NullUUID{UUID: feed.ID, Valid: true}}
+		http.Redirect(w, r, view.url(uuid.Nil), http.StatusSeeOther)
+		return nil
+	}
+}
+
+// Sets or clears the read flag, or the starred flag, from the reader's buttons, then goes back
+// to the page the button was on
+func webSetPostState(starred bool) apiHandler {
+	return func(s *state, w http.ResponseWriter, r *http.Request, user database.User) error {
+		post, err := lookupPost(s, user, r.PathValue("post"))
+		if err != nil {
+			return err
+		}
+
+		if starred {
+			set, err := strconv.ParseBool(r.FormValue("starred"))
+			if err != nil {
+				return apiErrorf(http.StatusBadRequest, "starred must be true or false")
+			}
+			err = s.db.SetPostStarred(r.Context(), database.SetPostStarredParams{
+				UserID:    user.ID,
+				PostID:    post.ID,
+				Starred:   set,
+				UpdatedAt: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			set, err := strconv.ParseBool(r.FormValue("read"))
+			if err != nil {
+				return apiErrorf(http.StatusBadRequest, "read must be true or false")
+			}
+			err = s.db.SetPostRead(r.Context(), database.SetPostReadParams{
+				UserID:    user.ID,
+				PostID:    post.ID,
+				Read:      set,
+				UpdatedAt: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// Only go back to a page of this site, never wherever the form says
+		back := r.FormValue("return")
+		if !strings.HasPrefix(back, "/") || strings.HasPrefix(back, "//") || strings.HasPrefix(back, "/\\") {
+			back = "/"
+		}
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return nil
+	}
+}
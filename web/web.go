@@ -0,0 +1,13 @@
+package web
+
+import "embed"
+
+// The pages of the web reader that gator serve provides, each one is parsed along with layout.html
+//
+//go:embed templates/*.html
+var Templates embed.FS
+
+// The stylesheet and anything else the web reader serves as it is, under /static/
+//
+//go:embed static
+var Static embed.FS
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Daxin319/Gator/internal/database"
+)
+
+// Sends a form to the web reader, with cookie as the session when it's set
+func webRequest(t *testing.T, handler http.Handler, method, path string, cookie *http.Cookie, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	if form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// Logs in to the web reader with an API token and returns the session cookie
+func webLogin(t *testing.T, handler http.Handler, token string) *http.Cookie {
+	t.Helper()
+	w := webRequest(t, handler, "POST", "/login", nil, url.Values{"token": {token}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("POST /login = %d %s", w.Code, w.Body)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookie {
+			return cookie
+		}
+	}
+	t.Fatal("POST /login set no session cookie")
+	return nil
+}
+
+// Reports whether a request got through to the reader rather than being sent to log in
+func loggedIn(t *testing.T, handler http.Handler, cookie *http.Cookie) bool {
+	t.Helper()
+	w := webRequest(t, handler, "GET", "/", cookie, nil)
+	if w.Code != http.StatusOK && (w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login") {
+		t.Fatalf("GET / = %d %s", w.Code, w.Body)
+	}
+	return w.Code == http.StatusOK
+}
+
+func TestWebSessions(t *testing.T) {
+	s, _ := newAPITestState(t)
+	handler := serveRoutes(s)
+
+	for _, token := range []string{"", "wrong"} {
+		w := webRequest(t, handler, "POST", "/login", nil, url.Values{"token": {token}})
+		if w.Code != http.StatusUnauthorized || len(w.Result().Cookies()) != 0 {
+			t.Errorf("POST /login with token %q = %d, cookies %v, want 401 and no cookie", token, w.Code, w.Result().Cookies())
+		}
+	}
+
+	first := webLogin(t, handler, testToken)
+	if strings.Contains(first.Value, testToken) {
+		t.Errorf("the session cookie %q holds the API token", first.Value)
+	}
+	if !loggedIn(t, handler, first) {
+		t.Error("the session from logging in doesn't get to the reader")
+	}
+	if loggedIn(t, handler, &http.Cookie{Name: sessionCookie, Value: testToken}) {
+		t.Error("the API token works as a session cookie")
+	}
+
+	// Logging out ends only the session it's sent with
+	second := webLogin(t, handler, testToken)
+	w := webRequest(t, handler, "POST", "/logout", first, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Fatalf("POST /logout = %d %s", w.Code, w.Body)
+	}
+	if loggedIn(t, handler, first) {
+		t.Error("the session still works after logging out")
+	}
+	if !loggedIn(t, handler, second) {
+		t.Error("logging out ended the other session too")
+	}
+
+	alice, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{UserID: alice.ID, Name: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if loggedIn(t, handler, second) {
+		t.Error("the session still works after its API token was revoked")
+	}
+}
+
+func TestWebReaderOnlyMarksReadOnPost(t *testing.T) {
+	handler, posts := newAPITestServer(t)
+	cookie := webLogin(t, handler, testToken)
+	id := posts["Errors"].ID.String()
+
+	isRead := func() bool {
+		t.Helper()
+		for _, post := range getTestPosts(t, handler, "").Posts {
+			if post.Title == "Errors" {
+				return post.Read
+			}
+		}
+		t.Fatal("Errors is missing")
+		return false
+	}
+
+	// Prefetching the page a post opens on mustn't mark it read
+	w := webRequest(t, handler, "GET", "/?post="+id, cookie, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Errors") {
+		t.Fatalf("GET /?post=%s = %d %s", id, w.Code, w.Body)
+	}
+	if isRead() {
+		t.Error("GET /?post= marked the post read")
+	}
+	if strings.Contains(w.Body.String(), `href="/?post=`) {
+		t.Error("the post list links straight to posts instead of marking them read on the way")
+	}
+
+	w = webRequest(t, handler, "POST", "/posts/"+id+"/read", cookie, url.Values{"read": {"true"}, "return": {"/?post=" + id}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/?post="+id {
+		t.Fatalf("POST /posts/%s/read = %d, Location %q", id, w.Code, w.Header().Get("Location"))
+	}
+	if !isRead() {
+		t.Error("opening the post from the list didn't mark it read")
+	}
+}